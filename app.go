@@ -1,12 +1,33 @@
 package main
 
 import (
+	"context"
+	"crypto/rsa"
+	"database/sql"
+	"errors"
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/chalkedgoose/act-up-api/blob"
+	"github.com/chalkedgoose/act-up-api/entity"
 	"github.com/chalkedgoose/act-up-api/graphql-definitions"
 	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/chalkedgoose/act-up-api/pubsub"
+	"github.com/chalkedgoose/act-up-api/usage"
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/exp/slog"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -16,15 +37,172 @@ func main() {
 		log.Fatalf("failed to create new newSchema, error: %v", err)
 	}
 
-	h := handler.New(&handler.Config{
+	databasePath := os.Getenv("DATABASE_PATH")
+	if databasePath == "" {
+		databasePath = "act-up.db"
+	}
+
+	db, err := sql.Open("sqlite", databasePath)
+
+	if err != nil {
+		log.Fatalf("failed to open database %q, error: %v", databasePath, err)
+	}
+	defer db.Close()
+
+	users, err := entity.NewSQLiteUserRepository(context.Background(), db)
+
+	if err != nil {
+		log.Fatalf("failed to prepare user repository, error: %v", err)
+	}
+
+	usageStore, err := usage.NewSQLiteStore(context.Background(), db)
+
+	if err != nil {
+		log.Fatalf("failed to prepare field usage store, error: %v", err)
+	}
+	fieldUsage := usage.NewRecorder(usageStore)
+	// the recorder flushes one last time once the server has shut down
+	usageCtx, stopUsage := context.WithCancel(context.Background())
+	usageDone := make(chan struct{})
+	go func() {
+		defer close(usageDone)
+		fieldUsage.Run(usageCtx, time.Minute)
+	}()
+	defer func() {
+		stopUsage()
+		<-usageDone
+	}()
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	mediaBaseURL := os.Getenv("MEDIA_BASE_URL")
+	if mediaBaseURL == "" {
+		mediaBaseURL = "http://localhost:8080/media/"
+	}
+
+	media, err := blob.NewFileStore(mediaDir, mediaBaseURL)
+
+	if err != nil {
+		log.Fatalf("failed to prepare media directory %q, error: %v", mediaDir, err)
+	}
+
+	var mediaSigner *blob.Signer
+	if secret := os.Getenv("MEDIA_SIGNING_SECRET"); secret != "" {
+		ttl := time.Hour
+		if value := os.Getenv("MEDIA_URL_TTL"); value != "" {
+			ttl, err = time.ParseDuration(value)
+
+			if err != nil {
+				log.Fatalf("failed to parse MEDIA_URL_TTL %q, error: %v", value, err)
+			}
+		}
+		mediaSigner = blob.NewSigner([]byte(secret), ttl)
+	}
+
+	var trustedDocuments *handler.TrustedDocuments
+	if path := os.Getenv("TRUSTED_DOCUMENTS_FILE"); path != "" {
+		trustedDocuments, err = handler.LoadTrustedDocuments(path)
+
+		if err != nil {
+			log.Fatalf("failed to load trusted documents, error: %v", err)
+		}
+		log.Printf("only the %d trusted documents in %s may be executed", trustedDocuments.Len(), path)
+	}
+
+	authenticator, err := newAuthenticator()
+
+	if err != nil {
+		log.Fatalf("failed to configure authentication, error: %v", err)
+	}
+
+	var wsInitFn handler.WebSocketInitFn
+	if authenticator != nil {
+		wsInitFn = authenticator.ConnectionInit
+	}
+
+	tracerProvider, err := newTracerProvider()
+
+	if err != nil {
+		log.Fatalf("failed to configure tracing, error: %v", err)
+	}
+	if tracerProvider != nil {
+		defer tracerProvider.Shutdown(context.Background())
+	}
+
+	metrics, err := handler.NewMetrics(prometheus.DefaultRegisterer)
+
+	if err != nil {
+		log.Fatalf("failed to register metrics, error: %v", err)
+	}
+
+	config := &handler.Config{
 		Schema:   &newSchema,
 		Pretty:   true,
 		GraphiQL: true,
-	})
+
+		MaxDepth:      12,
+		MaxComplexity: 2000,
+		MaxAliases:    30,
+		FieldCosts:    graphql_definitions.FieldCosts,
+
+		TrustedDocuments: trustedDocuments,
+
+		MaxBatchSize:     20,
+		BatchConcurrency: 4,
+
+		WebSocketInitFn: wsInitFn,
+
+		CSRFPrevention: true,
+
+		MaxUploadSize: 10 << 20,
+
+		Timeout: 10 * time.Second,
+
+		MaskInternalErrors: gin.Mode() == gin.ReleaseMode,
+
+		Metrics: metrics,
+
+		ApolloTracingOnRequest: gin.Mode() != gin.ReleaseMode,
+
+		Logger: slog.New(slog.NewJSONHandler(os.Stdout)),
+
+		FieldUsage: fieldUsage,
+	}
+	if tracerProvider != nil {
+		config.TracerProvider = tracerProvider
+	}
+	h := handler.New(config)
 
 	r := gin.Default()
 
-	r.Any("/graphql", gin.WrapH(h))
+	events := pubsub.New()
+
+	r.Use(func(c *gin.Context) {
+		ctx := entity.WithUserRepository(c.Request.Context(), users)
+		ctx = pubsub.WithPubSub(ctx, events)
+		ctx = blob.WithStore(ctx, media)
+		ctx = blob.WithSigner(ctx, mediaSigner)
+		ctx = usage.WithStore(ctx, usageStore)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+
+	var graphqlHandler http.Handler = h
+	if authenticator != nil {
+		graphqlHandler = authenticator.Middleware(h)
+	} else {
+		log.Println("no JWT keys configured, every request is anonymous")
+	}
+
+	r.Any("/graphql", gin.WrapH(graphqlHandler))
+
+	mediaHandler := gin.WrapH(http.StripPrefix("/media", blob.NewHandler(media, mediaSigner)))
+	r.GET("/media/*key", mediaHandler)
+	r.HEAD("/media/*key", mediaHandler)
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -32,9 +210,92 @@ func main() {
 		})
 	})
 
-	err = r.Run()
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	server := &http.Server{Addr: addr, Handler: r}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err != nil {
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-served:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("failed to serve, error: %v", err)
+		}
 		return
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+
+	if err != nil {
+		log.Printf("failed to shut down gracefully, error: %v", err)
+	}
+}
+
+// newTracerProvider exports spans as JSON to the file named by
+// OTEL_TRACES_FILE, or to stdout when OTEL_TRACES_EXPORTER is "stdout",
+// returning nil when neither is set.
+func newTracerProvider() (*sdktrace.TracerProvider, error) {
+	var out io.Writer
+	if path := os.Getenv("OTEL_TRACES_FILE"); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		out = file
+	} else if os.Getenv("OTEL_TRACES_EXPORTER") == "stdout" {
+		out = os.Stdout
+	} else {
+		return nil, nil
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)), nil
+}
+
+// newAuthenticator builds the JWT verifier from the environment, returning nil
+// when no keys are configured.
+func newAuthenticator() (*auth.Authenticator, error) {
+	config := auth.Config{
+		HMACSecret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+	}
+
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		keys, err := auth.LoadJWKS(path)
+		if err != nil {
+			return nil, err
+		}
+		config.RSAPublicKeys = keys
+	}
+
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		key, err := auth.LoadRSAPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if config.RSAPublicKeys == nil {
+			config.RSAPublicKeys = map[string]*rsa.PublicKey{}
+		}
+		config.RSAPublicKeys[""] = key
+	}
+
+	if len(config.HMACSecret) == 0 && len(config.RSAPublicKeys) == 0 {
+		return nil, nil
 	}
+	return auth.NewAuthenticator(config)
 }
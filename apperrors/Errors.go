@@ -0,0 +1,78 @@
+// Package apperrors defines the errors resolvers report to clients. Each
+// carries a Code that graphql-go copies into `extensions.code`; errors
+// without one are internal, and the handler hides their details from clients.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code classifies an error for clients.
+type Code string
+
+const (
+	NotFound        Code = "NOT_FOUND"
+	BadUserInput    Code = "BAD_USER_INPUT"
+	Unauthenticated Code = "UNAUTHENTICATED"
+	Forbidden       Code = "FORBIDDEN"
+	Internal        Code = "INTERNAL"
+)
+
+// Error is an error with a Code. Sentinels are compared by identity, so they
+// work with errors.Is.
+type Error struct {
+	Code    Code
+	Message string
+	// Field names the input that was rejected, for BadUserInput errors.
+	Field string
+	// Err is the underlying cause, if any; it is never shown to clients of
+	// internal errors.
+	Err error
+}
+
+// New returns an error with code and message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// NewBadUserInput reports that the value of field was rejected.
+func NewBadUserInput(field, message string) *Error {
+	return &Error{Code: BadUserInput, Message: message, Field: field}
+}
+
+// NewInternal wraps err as an internal error.
+func NewInternal(err error) *Error {
+	return &Error{Code: Internal, Err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Field != "":
+		return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+	case e.Message == "" && e.Err != nil:
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": string(e.Code)}
+	if e.Field != "" {
+		extensions["field"] = e.Field
+	}
+	return extensions
+}
+
+// CodeOf returns the Code of the first Error in err's chain, or Internal.
+func CodeOf(err error) Code {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return Internal
+}
@@ -0,0 +1,36 @@
+package apperrors_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+)
+
+func TestError(t *testing.T) {
+	notFound := apperrors.New(apperrors.NotFound, "user not found")
+	wrapped := fmt.Errorf("loading viewer: %w", notFound)
+	if !errors.Is(wrapped, notFound) || apperrors.CodeOf(wrapped) != apperrors.NotFound {
+		t.Fatalf("expected %v to be a NOT_FOUND error", wrapped)
+	}
+
+	input := apperrors.NewBadUserInput("name", "must not be empty")
+	if input.Error() != "invalid name: must not be empty" {
+		t.Fatalf("unexpected message %q", input.Error())
+	}
+	expected := map[string]interface{}{"code": "BAD_USER_INPUT", "field": "name"}
+	if !reflect.DeepEqual(input.Extensions(), expected) {
+		t.Fatalf("unexpected extensions %v", input.Extensions())
+	}
+
+	cause := errors.New("disk full")
+	internal := apperrors.NewInternal(cause)
+	if !errors.Is(internal, cause) || internal.Error() != "disk full" {
+		t.Fatalf("expected the internal error to wrap its cause, got %v", internal)
+	}
+	if apperrors.CodeOf(cause) != apperrors.Internal {
+		t.Fatal("expected errors without a code to be internal")
+	}
+}
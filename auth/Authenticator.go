@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrInvalidToken is returned for bearer tokens that fail verification.
+	ErrInvalidToken = errors.New("invalid bearer token")
+	errNoKeys       = errors.New("auth: neither an HS256 secret nor RS256 public keys are configured")
+)
+
+// Config holds the keys and expectations used to verify bearer tokens.
+type Config struct {
+	// HMACSecret verifies HS256 tokens when set.
+	HMACSecret []byte
+	// RSAPublicKeys verify RS256 tokens, selected by the token's `kid` header.
+	// A key stored under "" is used for tokens without a `kid`.
+	RSAPublicKeys map[string]*rsa.PublicKey
+	// Issuer and Audience, when set, must match the `iss` and `aud` claims.
+	Issuer   string
+	Audience string
+}
+
+// Authenticator verifies JWT bearer tokens from the Authorization header.
+type Authenticator struct {
+	config  Config
+	methods []string
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+}
+
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	a := &Authenticator{config: config}
+	if len(config.HMACSecret) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(config.RSAPublicKeys) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(a.methods) == 0 {
+		return nil, errNoKeys
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal of the request's bearer token, nil when the
+// request carries no Authorization header, or ErrInvalidToken.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, nil
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, fmt.Errorf("%w: expected a Bearer authorization", ErrInvalidToken)
+	}
+	return a.Verify(strings.TrimSpace(token))
+}
+
+// Verify checks the signature and claims of a compact JWT. Tokens must carry
+// an `exp` claim so a leaked token cannot be replayed forever.
+func (a *Authenticator) Verify(token string) (*Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, a.key, jwt.WithValidMethods(a.methods))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	if c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if a.config.Issuer != "" && !c.VerifyIssuer(a.config.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if a.config.Audience != "" && !c.VerifyAudience(a.config.Audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	return &Principal{
+		Subject: c.Subject,
+		Roles:   c.Roles,
+		Scopes:  strings.Fields(c.Scope),
+	}, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		return a.config.HMACSecret, nil
+	case jwt.SigningMethodRS256:
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.config.RSAPublicKeys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+}
+
+// Middleware stores the principal of each request in its context. Anonymous
+// requests pass through unchanged; requests with an invalid token are rejected
+// with 401 so clients notice an expired session instead of silently losing access.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"errors": []map[string]interface{}{{
+					"message":    err.Error(),
+					"extensions": map[string]interface{}{"code": "UNAUTHENTICATED"},
+				}},
+			})
+			return
+		}
+		if principal != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ConnectionInit authenticates a WebSocket connection from its connection_init
+// payload, where browsers put the token they cannot send as a header. Either an
+// `Authorization: Bearer <token>` entry or an `authToken` entry is accepted; a
+// payload without one keeps the connection anonymous.
+func (a *Authenticator) ConnectionInit(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+	token, _ := payload["authToken"].(string)
+	if header, ok := payload["Authorization"].(string); ok {
+		scheme, value, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return nil, fmt.Errorf("%w: expected a Bearer authorization", ErrInvalidToken)
+		}
+		token = value
+	}
+	if token == "" {
+		return ctx, nil
+	}
+
+	principal, err := a.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+	return WithPrincipal(ctx, principal), nil
+}
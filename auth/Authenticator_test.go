@@ -0,0 +1,155 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var secret = []byte("test-secret")
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func expires() int64 {
+	return time.Now().Add(time.Hour).Unix()
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	b, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuthenticator_HS256(t *testing.T) {
+	a, err := auth.NewAuthenticator(auth.Config{HMACSecret: secret, Issuer: "act-up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{
+		"sub":   "42",
+		"iss":   "act-up",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"ADMIN"},
+		"scope": "users:read users:write",
+	})
+	p, err := a.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &auth.Principal{Subject: "42", Roles: []string{"ADMIN"}, Scopes: []string{"users:read", "users:write"}}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+}
+
+func TestAuthenticator_RS256FromJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadJWKS(writeJWKS(t, "k1", &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuthenticator(auth.Config{RSAPublicKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := a.Verify(sign(t, jwt.SigningMethodRS256, key, "k1", jwt.MapClaims{"sub": "7", "exp": expires()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "7" {
+		t.Fatalf("unexpected subject %q", p.Subject)
+	}
+
+	_, err = a.Verify(sign(t, jwt.SigningMethodRS256, key, "other", jwt.MapClaims{"sub": "7", "exp": expires()}))
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expected unknown kid to be rejected, got %v", err)
+	}
+}
+
+func TestAuthenticator_Rejects(t *testing.T) {
+	a, _ := auth.NewAuthenticator(auth.Config{HMACSecret: secret, Audience: "mobile"})
+
+	tests := map[string]string{
+		"expired":        sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "1", "aud": "mobile", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"no expiry":      sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "1", "aud": "mobile"}),
+		"wrong secret":   sign(t, jwt.SigningMethodHS256, []byte("nope"), "", jwt.MapClaims{"sub": "1", "aud": "mobile", "exp": expires()}),
+		"wrong alg":      sign(t, jwt.SigningMethodHS384, secret, "", jwt.MapClaims{"sub": "1", "aud": "mobile", "exp": expires()}),
+		"no subject":     sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"aud": "mobile", "exp": expires()}),
+		"wrong audience": sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "1", "aud": "web", "exp": expires()}),
+		"garbage":        "not.a.token",
+	}
+	for name, token := range tests {
+		if _, err := a.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	a, _ := auth.NewAuthenticator(auth.Config{HMACSecret: secret})
+
+	var seen *auth.Principal
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = auth.PrincipalFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != nil {
+		t.Fatalf("expected anonymous request, got %+v", seen)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "3", "exp": expires()}))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == nil || seen.Subject != "3" {
+		t.Fatalf("expected principal for subject 3, got %+v", seen)
+	}
+
+	seen = nil
+	req.Header.Set("Authorization", "Bearer not.a.token")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusUnauthorized || seen != nil {
+		t.Fatalf("expected 401 without reaching the handler, got %v", resp.Code)
+	}
+}
@@ -0,0 +1,9 @@
+package auth
+
+import "github.com/chalkedgoose/act-up-api/apperrors"
+
+// ErrUnauthenticated is returned by resolvers that need a principal when the request has none.
+var ErrUnauthenticated error = apperrors.New(apperrors.Unauthenticated, "authentication required")
+
+// ErrForbidden is returned in place of a field the principal is not allowed to resolve.
+var ErrForbidden error = apperrors.New(apperrors.Forbidden, "not authorized to access this field")
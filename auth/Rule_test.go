@@ -0,0 +1,79 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/graphql-go/graphql"
+)
+
+func TestRule_Allows(t *testing.T) {
+	rule := auth.Rule{Roles: []string{"ADMIN", "MODERATOR"}, Scopes: []string{"users:write"}}
+
+	tests := []struct {
+		principal *auth.Principal
+		allowed   bool
+	}{
+		{nil, false},
+		{&auth.Principal{Roles: []string{"MODERATOR"}, Scopes: []string{"users:read", "users:write"}}, true},
+		{&auth.Principal{Roles: []string{"MODERATOR"}, Scopes: []string{"users:read"}}, false},
+		{&auth.Principal{Roles: []string{"MEMBER"}, Scopes: []string{"users:write"}}, false},
+	}
+	for i, tt := range tests {
+		if got := rule.Allows(tt.principal); got != tt.allowed {
+			t.Errorf("case %d: expected %v, got %v", i, tt.allowed, got)
+		}
+	}
+}
+
+func TestProtect_DeniedFieldResolvesToNull(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"public": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return "hello", nil
+				},
+			},
+			"secret": auth.Protect(auth.RequireRole(auth.RoleAdmin), &graphql.Field{
+				Type:        graphql.String,
+				Description: "Only for admins",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return "s3cr3t", nil
+				},
+			}),
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if desc := query.Fields()["secret"].Description; desc != "Only for admins. Requires role ADMIN." {
+		t.Fatalf("unexpected description %q", desc)
+	}
+
+	tests := map[string]string{
+		"anonymous": `{"data":{"public":"hello","secret":null},"errors":[{"message":"authentication required","locations":[{"line":1,"column":10}],"path":["secret"],"extensions":{"code":"UNAUTHENTICATED"}}]}`,
+		"member":    `{"data":{"public":"hello","secret":null},"errors":[{"message":"not authorized to access this field","locations":[{"line":1,"column":10}],"path":["secret"],"extensions":{"code":"FORBIDDEN"}}]}`,
+		"admin":     `{"data":{"public":"hello","secret":"s3cr3t"}}`,
+	}
+	principals := map[string]*auth.Principal{
+		"member": {Subject: "1", Roles: []string{"MEMBER"}},
+		"admin":  {Subject: "2", Roles: []string{auth.RoleAdmin}},
+	}
+	for name, expected := range tests {
+		ctx := context.Background()
+		if p := principals[name]; p != nil {
+			ctx = auth.WithPrincipal(ctx, p)
+		}
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ public secret }`, Context: ctx})
+		b, _ := json.Marshal(result)
+		if string(b) != expected {
+			t.Errorf("%s: unexpected result\n got: %s\nwant: %s", name, b, expected)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package avatar turns uploaded images into the square variants served as user avatars.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes are the edge lengths in pixels of the variants generated for every avatar, ascending.
+var Sizes = []int{64, 128, 350}
+
+// maxPixels bounds the dimensions of decoded images, so a small but highly
+// compressed upload cannot exhaust memory. It is far more than an avatar needs.
+const maxPixels = 4096 * 4096
+
+var (
+	ErrUnsupportedFormat = errors.New("must be a GIF, JPEG, PNG or WebP image")
+	ErrTooManyPixels     = fmt.Errorf("must not have more than %d pixels", maxPixels)
+)
+
+// Image holds the encoded variants of one avatar.
+type Image struct {
+	// Ext is the file extension of every variant, e.g. ".png".
+	Ext string
+	// Variants maps each of Sizes to its encoded image.
+	Variants map[int][]byte
+}
+
+// Process checks that data holds a supported image by sniffing its contents,
+// then crops it to a centered square and encodes one variant per entry of
+// Sizes. Re-encoding drops EXIF and other metadata; the EXIF orientation of
+// JPEGs is applied to every variant so photos keep facing up.
+func Process(data []byte) (*Image, error) {
+	format := http.DetectContentType(data)
+	switch format {
+	case "image/gif", "image/jpeg", "image/png", "image/webp":
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	orientation := 1
+	if format == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// JPEG stays JPEG; everything else may be transparent and becomes PNG
+	ext, encode := ".png", func(buf *bytes.Buffer, img image.Image) error {
+		return png.Encode(buf, img)
+	}
+	if format == "image/jpeg" {
+		ext, encode = ".jpg", func(buf *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+		}
+	}
+
+	// orienting commutes with taking the centered square, so it is applied to
+	// the scaled variants instead of the full upload
+	square := centerSquare(src.Bounds())
+	result := &Image{Ext: ext, Variants: make(map[int][]byte, len(Sizes))}
+	for _, size := range Sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, square, draw.Src, nil)
+
+		var buf bytes.Buffer
+		if err := encode(&buf, orient(dst, orientation)); err != nil {
+			return nil, err
+		}
+		result.Variants[size] = buf.Bytes()
+	}
+	return result, nil
+}
+
+// centerSquare returns the largest square centered in r.
+func centerSquare(r image.Rectangle) image.Rectangle {
+	edge := r.Dx()
+	if r.Dy() < edge {
+		edge = r.Dy()
+	}
+	min := r.Min.Add(image.Pt((r.Dx()-edge)/2, (r.Dy()-edge)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(edge, edge))}
+}
+
+// Key returns the blob key of the size variant of an avatar stored below dir.
+func Key(dir string, size int, ext string) string {
+	return fmt.Sprintf("%s/%d%s", dir, size, ext)
+}
+
+// VariantKey returns the key of the size variant of the avatar that key is
+// a variant of, or false when key was not created by Key.
+func VariantKey(key string, size int) (string, bool) {
+	dir, file := path.Split(key)
+	ext := path.Ext(file)
+	variant, err := strconv.Atoi(strings.TrimSuffix(file, ext))
+	if err != nil || !isSize(variant) {
+		return "", false
+	}
+	return Key(strings.TrimSuffix(dir, "/"), size, ext), true
+}
+
+// Closest returns the smallest of Sizes that is at least size, or the
+// largest one when size exceeds them all.
+func Closest(size int) int {
+	for _, s := range Sizes {
+		if s >= size {
+			return s
+		}
+	}
+	return Sizes[len(Sizes)-1]
+}
+
+func isSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package avatar_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/chalkedgoose/act-up-api/avatar"
+)
+
+// halves returns a w×h image whose left half is red and right half is blue.
+func halves(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF APP1 segment with the given orientation
+// right after the SOI marker of a JPEG.
+func withOrientation(jpg []byte, orientation byte) []byte {
+	exif := []byte("Exif\x00\x00" +
+		"MM\x00\x2a\x00\x00\x00\x08" + // big endian TIFF header, IFD0 at offset 8
+		"\x00\x01" + // one entry
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string([]byte{orientation}) + "\x00\x00" +
+		"\x00\x00\x00\x00")
+	segment := append([]byte{0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestProcess_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, halves(300, 100))
+
+	processed, err := avatar.Process(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.Ext != ".png" {
+		t.Fatalf("expected .png, got %s", processed.Ext)
+	}
+	for _, size := range avatar.Sizes {
+		img, format, err := image.Decode(bytes.NewReader(processed.Variants[size]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != "png" || img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+			t.Fatalf("variant %d is a %s of %v", size, format, img.Bounds())
+		}
+	}
+}
+
+func TestProcess_JPEGOrientationAndMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, halves(40, 20), &jpeg.Options{Quality: 100})
+	upload := withOrientation(buf.Bytes(), 6)
+
+	processed, err := avatar.Process(upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.Ext != ".jpg" {
+		t.Fatalf("expected .jpg, got %s", processed.Ext)
+	}
+	variant := processed.Variants[64]
+	if bytes.Contains(variant, []byte("Exif")) {
+		t.Fatal("expected EXIF metadata to be stripped")
+	}
+
+	// rotated clockwise the red left half ends up on top
+	img, err := jpeg.Decode(bytes.NewReader(variant))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, b, _ := img.At(60, 4).RGBA()
+	if r < b {
+		t.Fatalf("expected the top right corner to be red after applying the orientation, got %v", img.At(60, 4))
+	}
+	r, _, b, _ = img.At(4, 60).RGBA()
+	if b < r {
+		t.Fatalf("expected the bottom left corner to be blue after applying the orientation, got %v", img.At(4, 60))
+	}
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	if _, err := avatar.Process([]byte("<html><script>alert(1)</script></html>")); err != avatar.ErrUnsupportedFormat {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	// a PNG signature without a valid image behind it
+	if _, err := avatar.Process([]byte("\x89PNG\r\n\x1a\nnot really")); err != avatar.ErrUnsupportedFormat {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4097, 4096)))
+	if _, err := avatar.Process(buf.Bytes()); err != avatar.ErrTooManyPixels {
+		t.Fatalf("expected ErrTooManyPixels, got %v", err)
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	key := avatar.Key("avatars/2/ab12", 350, ".png")
+	if key != "avatars/2/ab12/350.png" {
+		t.Fatalf("unexpected key %s", key)
+	}
+	if variant, ok := avatar.VariantKey(key, 64); !ok || variant != "avatars/2/ab12/64.png" {
+		t.Fatalf("unexpected variant %s, %v", variant, ok)
+	}
+	if _, ok := avatar.VariantKey("avatars/2/ab12.png", 64); ok {
+		t.Fatal("expected a key not created by Key to have no variants")
+	}
+	for size, expected := range map[int]int{1: 64, 64: 64, 65: 128, 200: 350, 1000: 350} {
+		if closest := avatar.Closest(size); closest != expected {
+			t.Errorf("Closest(%d) = %d, expected %d", size, closest, expected)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chalkedgoose/act-up-api/blob"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewFileStore(t.TempDir(), "http://localhost:8080/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(ctx, "avatars/1/a.png", strings.NewReader("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "avatars/1/a.png", strings.NewReader("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := store.Open(ctx, "avatars/1/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, _ := io.ReadAll(object)
+	object.Close()
+	if string(contents) != "second" || object.Size != 6 {
+		t.Fatalf("unexpected blob %q of size %d", contents, object.Size)
+	}
+
+	if url := store.URL("avatars/1/a.png"); url != "http://localhost:8080/media/avatars/1/a.png" {
+		t.Fatalf("unexpected URL %s", url)
+	}
+
+	if err := store.Delete(ctx, "avatars/1/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "avatars/1/a.png"); err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+	if _, err := store.Open(ctx, "avatars/1/a.png"); err != blob.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Open(ctx, "avatars/1"); err != blob.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for a directory, got %v", err)
+	}
+}
+
+func TestFileStore_InvalidKeys(t *testing.T) {
+	store, err := blob.NewFileStore(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../secret", "a//b", ".."} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); err != blob.ErrInvalidKey {
+			t.Errorf("%q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// immutableMaxAge is how long clients may cache blobs. Keys are derived from
+// the contents they hold and never rewritten with different data.
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// Handler serves the blobs of a Store over HTTP, with the key being the
+// request path; mount it with http.StripPrefix. Conditional and Range
+// requests are answered by http.ServeContent.
+type Handler struct {
+	store  Store
+	signer *Signer
+}
+
+// NewHandler serves store. When signer is not nil only URLs it signed are served.
+func NewHandler(store Store, signer *Signer) *Handler {
+	return &Handler{store: store, signer: signer}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+
+	cacheControl := fmt.Sprintf("public, max-age=%d, immutable", int(immutableMaxAge.Seconds()))
+	if h.signer != nil {
+		expiry, err := h.signer.Verify(key, r.URL.Query())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(time.Until(expiry).Seconds()))
+	}
+
+	object, err := h.store.Open(r.Context(), key)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer object.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, object.ModTime.UnixNano(), object.Size))
+	http.ServeContent(w, r, key, object.ModTime, object)
+}
@@ -0,0 +1,92 @@
+package blob_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/blob"
+)
+
+func newMediaServer(t *testing.T, signer *blob.Signer) (*blob.FileStore, *httptest.Server) {
+	store, err := blob.NewFileStore(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), "avatars/2/ab/64.png", strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.StripPrefix("/media", blob.NewHandler(store, signer)))
+	t.Cleanup(server.Close)
+	return store, server
+}
+
+func get(t *testing.T, url string, headers map[string]string) *http.Response {
+	req, _ := http.NewRequest("GET", url, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestHandler(t *testing.T) {
+	_, server := newMediaServer(t, nil)
+	url := server.URL + "/media/avatars/2/ab/64.png"
+
+	resp := get(t, url, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" || resp.ContentLength != 10 {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); !strings.Contains(cacheControl, "immutable") {
+		t.Fatalf("expected an immutable Cache-Control, got %q", cacheControl)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" || resp.Header.Get("Last-Modified") == "" {
+		t.Fatalf("expected validators, got %v", resp.Header)
+	}
+
+	if resp := get(t, url, map[string]string{"If-None-Match": etag}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", resp.StatusCode)
+	}
+	resp = get(t, url, map[string]string{"Range": "bytes=2-5"})
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != "bytes 2-5/10" {
+		t.Fatalf("unexpected range response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	for _, path := range []string{"/media/avatars/2/ab/128.png", "/media/../secret", "/media/avatars"} {
+		if resp := get(t, server.URL+path, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandler_SignedURLs(t *testing.T) {
+	signer := blob.NewSigner([]byte("secret"), time.Hour)
+	store, server := newMediaServer(t, signer)
+
+	signed := signer.URL(store, "avatars/2/ab/64.png")
+	if !strings.HasPrefix(signed, "/media/avatars/2/ab/64.png?expires=") {
+		t.Fatalf("unexpected signed URL %s", signed)
+	}
+	resp := get(t, server.URL+signed, nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Cache-Control"), "private") {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	expired := blob.NewSigner([]byte("secret"), -2*time.Minute).URL(store, "avatars/2/ab/64.png")
+	forged := blob.NewSigner([]byte("guess"), time.Hour).URL(store, "avatars/2/ab/64.png")
+	otherKey := strings.Replace(signed, "64.png", "128.png", 1)
+	for _, url := range []string{"/media/avatars/2/ab/64.png", expired, forged, otherKey} {
+		if resp := get(t, server.URL+url, nil); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", url, resp.StatusCode)
+		}
+	}
+}
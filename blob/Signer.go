@@ -0,0 +1,81 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidSignature is returned by Signer.Verify for URLs that were not
+// signed with its secret or whose signature has expired.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Signer issues blob URLs that stop working after a while, so media behind a
+// Handler using it cannot be enumerated or linked to permanently.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner signs URLs with secret that stay valid for at least ttl.
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{secret: secret, ttl: ttl}
+}
+
+// URL returns the URL of key in store carrying an expiry and signature.
+// Expiries are rounded up to the minute, so URLs issued close together are
+// identical and stay cacheable.
+func (s *Signer) URL(store Store, key string) string {
+	expires := time.Now().Add(s.ttl).Truncate(time.Minute).Add(time.Minute).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.signature(key, expires))
+	return store.URL(key) + "?" + query.Encode()
+}
+
+// Verify checks the expiry and signature in the query of a request for key,
+// returning when the URL expires.
+func (s *Signer) Verify(key string, query url.Values) (time.Time, error) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidSignature
+	}
+	expected := s.signature(key, expires)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(expected)) {
+		return time.Time{}, ErrInvalidSignature
+	}
+	expiry := time.Unix(expires, 0)
+	if !time.Now().Before(expiry) {
+		return time.Time{}, ErrInvalidSignature
+	}
+	return expiry, nil
+}
+
+func (s *Signer) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type signerKey struct{}
+
+// WithSigner returns a copy of ctx carrying signer, so resolvers hand out signed URLs.
+func WithSigner(ctx context.Context, signer *Signer) context.Context {
+	return context.WithValue(ctx, signerKey{}, signer)
+}
+
+// SignerFromContext returns the Signer stored by WithSigner.
+func SignerFromContext(ctx context.Context) (*Signer, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	signer, ok := ctx.Value(signerKey{}).(*Signer)
+	return signer, ok && signer != nil
+}
@@ -0,0 +1,51 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Open for keys that hold no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or climb out of
+// the store with "..".
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store keeps uploaded files. Keys are slash separated paths such as
+// "avatars/1/8f2c.png"; their extension decides the Content-Type they are served with.
+type Store interface {
+	// Put stores the contents of r under key, replacing any previous blob.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob under key or ErrNotFound.
+	Open(ctx context.Context, key string) (*Object, error)
+	// Delete removes the blob under key; deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the absolute URL the blob under key is served at.
+	URL(key string) string
+}
+
+// Object is an open blob. Callers must close it.
+type Object struct {
+	io.ReadSeekCloser
+	Size    int64
+	ModTime time.Time
+}
+
+type storeKey struct{}
+
+// WithStore returns a copy of ctx carrying store, so resolvers can save uploads.
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// FromContext returns the Store stored by WithStore.
+func FromContext(ctx context.Context) (Store, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	store, ok := ctx.Value(storeKey{}).(Store)
+	return store, ok
+}
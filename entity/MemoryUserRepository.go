@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryUserRepository is a UserRepository kept entirely in memory, intended for tests.
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func NewMemoryUserRepository(users ...User) *MemoryUserRepository {
+	r := &MemoryUserRepository{users: make(map[string]User, len(users))}
+	for _, u := range users {
+		r.users[u.ID] = u
+	}
+	return r
+}
+
+func (r *MemoryUserRepository) Get(ctx context.Context, id string) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, opts UserListOptions) ([]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]User, 0, len(r.users))
+	for _, u := range r.users {
+		if opts.matches(u) {
+			users = append(users, u)
+		}
+	}
+	opts.sortUsers(users)
+	return users, nil
+}
+
+func (r *MemoryUserRepository) Page(ctx context.Context, q UserPageQuery) (UserPage, error) {
+	users, err := r.List(ctx, UserListOptions{})
+	if err != nil {
+		return UserPage{}, err
+	}
+	return pageUsers(users, q), nil
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, u User) (User, error) {
+	u, err := prepareNew(u)
+	if err != nil {
+		return User{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[u.ID]; exists {
+		return User{}, fmt.Errorf("user %q already exists", u.ID)
+	}
+	r.users[u.ID] = u
+	return u, nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, u User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	stored.Name = u.Name
+	stored.AvatarURL = u.AvatarURL
+	r.users[u.ID] = stored
+	return stored, nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrUserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
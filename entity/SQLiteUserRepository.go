@@ -0,0 +1,232 @@
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const createUsersTable = `
+CREATE TABLE IF NOT EXISTS users (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	avatar_url TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS users_created_at_id ON users (created_at, id)`
+
+// SQLiteUserRepository is a UserRepository backed by an embedded SQLite database.
+// The caller owns db and is responsible for registering a driver and closing it.
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteUserRepository creates the users table if needed and returns a repository using db.
+func NewSQLiteUserRepository(ctx context.Context, db *sql.DB) (*SQLiteUserRepository, error) {
+	if _, err := db.ExecContext(ctx, createUsersTable); err != nil {
+		return nil, err
+	}
+	return &SQLiteUserRepository{db: db}, nil
+}
+
+func (r *SQLiteUserRepository) Get(ctx context.Context, id string) (User, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT id, name, avatar_url, created_at FROM users WHERE id = ?`, id)
+
+	u, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func (r *SQLiteUserRepository) List(ctx context.Context, opts UserListOptions) ([]User, error) {
+	var conditions []string
+	var args []interface{}
+	if opts.NameContains != "" {
+		conditions = append(conditions, `instr(lower(name), lower(?)) > 0`)
+		args = append(args, opts.NameContains)
+	}
+	if opts.Search != "" {
+		conditions = append(conditions, `instr(lower(name), lower(?)) > 0`)
+		args = append(args, opts.Search)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		conditions = append(conditions, `created_at > ?`)
+		args = append(args, opts.CreatedAfter.UnixNano())
+	}
+	if opts.IDs != nil {
+		if len(opts.IDs) == 0 {
+			return []User{}, nil
+		}
+		conditions = append(conditions, `id IN (?`+strings.Repeat(`, ?`, len(opts.IDs)-1)+`)`)
+		for _, id := range opts.IDs {
+			args = append(args, id)
+		}
+	}
+
+	query := `SELECT id, name, avatar_url, created_at FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	switch {
+	case opts.OrderBy == OrderByDefault && opts.Search != "":
+		query += ` ORDER BY instr(lower(name), lower(?)) = 1 DESC, lower(name), id`
+		args = append(args, opts.Search)
+	case opts.OrderBy == OrderByNameAsc:
+		query += ` ORDER BY lower(name), id`
+	case opts.OrderBy == OrderByNameDesc:
+		query += ` ORDER BY lower(name) DESC, id DESC`
+	case opts.OrderBy == OrderByCreatedAtDesc:
+		query += ` ORDER BY created_at DESC, id DESC`
+	default:
+		query += ` ORDER BY created_at, id`
+	}
+	return r.queryUsers(ctx, query, args...)
+}
+
+func (r *SQLiteUserRepository) Page(ctx context.Context, q UserPageQuery) (UserPage, error) {
+	var conditions []string
+	var args []interface{}
+	if q.After != nil {
+		cond, condArgs := keysetCondition(">", *q.After)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+	if q.Before != nil {
+		cond, condArgs := keysetCondition("<", *q.Before)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	// paging backwards reads the newest rows first and flips them afterwards
+	order, limit := "created_at, id", q.First
+	if q.Last > 0 {
+		order, limit = "created_at DESC, id DESC", q.Last
+	}
+
+	query := `SELECT id, name, avatar_url, created_at FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` ORDER BY ` + order + ` LIMIT ?`
+
+	users, err := r.queryUsers(ctx, query, append(args, limit)...)
+	if err != nil {
+		return UserPage{}, err
+	}
+	if q.Last > 0 {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	page := UserPage{Users: users}
+	if len(users) == 0 {
+		return page, nil
+	}
+	if page.HasPrevious, err = r.exists(ctx, "<", CursorOf(users[0])); err != nil {
+		return UserPage{}, err
+	}
+	if page.HasNext, err = r.exists(ctx, ">", CursorOf(users[len(users)-1])); err != nil {
+		return UserPage{}, err
+	}
+	return page, nil
+}
+
+// keysetCondition matches rows sorting strictly before ("<") or after (">") c.
+func keysetCondition(op string, c UserCursor) (string, []interface{}) {
+	n := c.CreatedAt.UnixNano()
+	cond := "(created_at " + op + " ? OR (created_at = ? AND id " + op + " ?))"
+	return cond, []interface{}{n, n, c.ID}
+}
+
+func (r *SQLiteUserRepository) exists(ctx context.Context, op string, c UserCursor) (bool, error) {
+	cond, args := keysetCondition(op, c)
+	var found bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE `+cond+`)`, args...).Scan(&found)
+	return found, err
+}
+
+func (r *SQLiteUserRepository) queryUsers(ctx context.Context, query string, args ...interface{}) ([]User, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var u User
+	var createdAt int64
+	if err := row.Scan(&u.ID, &u.Name, &u.AvatarURL, &createdAt); err != nil {
+		return User{}, err
+	}
+	u.CreatedAt = time.Unix(0, createdAt).UTC()
+	return u, nil
+}
+
+func (r *SQLiteUserRepository) Create(ctx context.Context, u User) (User, error) {
+	u, err := prepareNew(u)
+	if err != nil {
+		return User{}, err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO users (id, name, avatar_url, created_at) VALUES (?, ?, ?, ?)`,
+		u.ID, u.Name, u.AvatarURL, u.CreatedAt.UnixNano())
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (r *SQLiteUserRepository) Update(ctx context.Context, u User) (User, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE users SET name = ?, avatar_url = ? WHERE id = ?`,
+		u.Name, u.AvatarURL, u.ID)
+	if err != nil {
+		return User{}, err
+	}
+	if err := expectOneRow(res); err != nil {
+		return User{}, err
+	}
+	return r.Get(ctx, u.ID)
+}
+
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// expectOneRow maps a statement that touched no rows to ErrUserNotFound.
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -1,7 +1,39 @@
 package entity
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+)
+
 type User struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	AvatarURL string `json:"avatarURL"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	AvatarURL string    `json:"avatarURL"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const maxUserNameLength = 100
+
+// Validate checks the fields a client is allowed to set on a user.
+func (u User) Validate() error {
+	name := strings.TrimSpace(u.Name)
+	if name == "" {
+		return apperrors.NewBadUserInput("name", "must not be empty")
+	}
+	if utf8.RuneCountInString(name) > maxUserNameLength {
+		return apperrors.NewBadUserInput("name", fmt.Sprintf("must be at most %d characters", maxUserNameLength))
+	}
+
+	if u.AvatarURL != "" {
+		avatar, err := url.Parse(u.AvatarURL)
+		if err != nil || (avatar.Scheme != "http" && avatar.Scheme != "https") || avatar.Host == "" {
+			return apperrors.NewBadUserInput("avatarURL", "must be an absolute http or https URL")
+		}
+	}
+	return nil
 }
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// UserOrder selects the sort order of UserRepository.List.
+type UserOrder int
+
+const (
+	// OrderByDefault lists users in creation order, or by relevance to Search when it is set.
+	OrderByDefault UserOrder = iota
+	OrderByCreatedAtAsc
+	OrderByCreatedAtDesc
+	OrderByNameAsc
+	OrderByNameDesc
+)
+
+// UserListOptions narrows and orders UserRepository.List. The zero value lists
+// every user in creation order. Text matching is case-insensitive; the SQLite
+// store only folds ASCII letters.
+type UserListOptions struct {
+	// NameContains keeps users whose name contains the given text.
+	NameContains string
+	// IDs keeps only users with one of the given IDs when non-nil.
+	IDs []string
+	// CreatedAfter keeps users created strictly after the given instant when non-zero.
+	CreatedAfter time.Time
+	// Search keeps users whose name contains the given text. With OrderByDefault,
+	// names starting with it are ranked first.
+	Search  string
+	OrderBy UserOrder
+}
+
+// matches reports whether u passes every filter in o.
+func (o UserListOptions) matches(u User) bool {
+	name := strings.ToLower(u.Name)
+	if o.NameContains != "" && !strings.Contains(name, strings.ToLower(o.NameContains)) {
+		return false
+	}
+	if o.Search != "" && !strings.Contains(name, strings.ToLower(o.Search)) {
+		return false
+	}
+	if !o.CreatedAfter.IsZero() && !u.CreatedAt.After(o.CreatedAfter) {
+		return false
+	}
+	if o.IDs != nil {
+		for _, id := range o.IDs {
+			if id == u.ID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// sortUsers orders users as List does for o.
+func (o UserListOptions) sortUsers(users []User) {
+	rankPrefix := o.Search != "" && o.OrderBy == OrderByDefault
+	search := strings.ToLower(o.Search)
+
+	sort.Slice(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if rankPrefix {
+			ap := strings.HasPrefix(strings.ToLower(a.Name), search)
+			bp := strings.HasPrefix(strings.ToLower(b.Name), search)
+			if ap != bp {
+				return ap
+			}
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn
+			}
+			return a.ID < b.ID
+		}
+
+		switch o.OrderBy {
+		case OrderByNameAsc, OrderByNameDesc:
+			an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name)
+			if an != bn {
+				return (an < bn) == (o.OrderBy == OrderByNameAsc)
+			}
+		case OrderByCreatedAtDesc:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+		default:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		if o.OrderBy == OrderByNameDesc || o.OrderBy == OrderByCreatedAtDesc {
+			return a.ID > b.ID
+		}
+		return a.ID < b.ID
+	})
+}
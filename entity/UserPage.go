@@ -0,0 +1,69 @@
+package entity
+
+import "time"
+
+// UserCursor is a position in the (CreatedAt, ID) ordering used for keyset paging.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorOf returns the keyset position of u.
+func CursorOf(u User) UserCursor {
+	return UserCursor{CreatedAt: u.CreatedAt, ID: u.ID}
+}
+
+// less reports whether position a sorts strictly before position b.
+func (a UserCursor) less(b UserCursor) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// UserPageQuery selects a window of users. Exactly one of First or Last must be positive;
+// After and Before optionally restrict the window to users strictly between two cursors.
+type UserPageQuery struct {
+	First  int
+	After  *UserCursor
+	Last   int
+	Before *UserCursor
+}
+
+// UserPage is one window of users in creation order. HasNext and HasPrevious report
+// whether any user sorts after the last or before the first user of the window.
+type UserPage struct {
+	Users       []User
+	HasNext     bool
+	HasPrevious bool
+}
+
+// pageUsers applies q to users, which must already be in (CreatedAt, ID) order.
+func pageUsers(users []User, q UserPageQuery) UserPage {
+	window := []User{}
+	for _, u := range users {
+		c := CursorOf(u)
+		if q.After != nil && !q.After.less(c) {
+			continue
+		}
+		if q.Before != nil && !c.less(*q.Before) {
+			continue
+		}
+		window = append(window, u)
+	}
+
+	if q.Last > 0 {
+		if len(window) > q.Last {
+			window = window[len(window)-q.Last:]
+		}
+	} else if len(window) > q.First {
+		window = window[:q.First]
+	}
+
+	page := UserPage{Users: window}
+	if len(window) > 0 {
+		page.HasPrevious = CursorOf(users[0]).less(CursorOf(window[0]))
+		page.HasNext = CursorOf(window[len(window)-1]).less(CursorOf(users[len(users)-1]))
+	}
+	return page
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+)
+
+// ErrUserNotFound is returned by a UserRepository when no user has the requested ID.
+var ErrUserNotFound error = apperrors.New(apperrors.NotFound, "user not found")
+
+// UserRepository loads users from persistent storage.
+type UserRepository interface {
+	// Get returns the user with the given ID, or ErrUserNotFound.
+	Get(ctx context.Context, id string) (User, error)
+	// List returns the users matching opts in the order it requests.
+	List(ctx context.Context, opts UserListOptions) ([]User, error)
+	// Page returns the window of users selected by q, in creation order.
+	Page(ctx context.Context, q UserPageQuery) (UserPage, error)
+	// Create stores a new user, assigning its ID and CreatedAt when they are empty.
+	Create(ctx context.Context, u User) (User, error)
+	// Update replaces the stored name and avatar of u.ID, or returns ErrUserNotFound.
+	Update(ctx context.Context, u User) (User, error)
+	// Delete removes the user with the given ID, or returns ErrUserNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// newUserID returns a random identifier for a user created without one.
+func newUserID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// prepareNew fills in the fields Create is responsible for assigning.
+func prepareNew(u User) (User, error) {
+	if u.ID == "" {
+		id, err := newUserID()
+		if err != nil {
+			return User{}, err
+		}
+		u.ID = id
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	u.CreatedAt = u.CreatedAt.UTC()
+	return u, nil
+}
+
+type userRepositoryKey struct{}
+
+// WithUserRepository returns a copy of ctx carrying repo, so resolvers can reach the store.
+func WithUserRepository(ctx context.Context, repo UserRepository) context.Context {
+	return context.WithValue(ctx, userRepositoryKey{}, repo)
+}
+
+// UserRepositoryFromContext returns the repository stored by WithUserRepository.
+func UserRepositoryFromContext(ctx context.Context) (UserRepository, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	repo, ok := ctx.Value(userRepositoryKey{}).(UserRepository)
+	return repo, ok
+}
@@ -0,0 +1,221 @@
+package entity_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/entity"
+	_ "modernc.org/sqlite"
+)
+
+var fixtureUsers = []entity.User{
+	{ID: "2", Name: "Haley Levesque", AvatarURL: "https://picsum.photos/350", CreatedAt: time.Unix(200, 0).UTC()},
+	{ID: "1", Name: "Carlos Alba", AvatarURL: "https://picsum.photos/350", CreatedAt: time.Unix(100, 0).UTC()},
+}
+
+func newSQLiteRepository(t *testing.T, users ...entity.User) entity.UserRepository {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a fresh database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := entity.NewSQLiteUserRepository(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		_, err := db.Exec(`INSERT INTO users (id, name, avatar_url, created_at) VALUES (?, ?, ?, ?)`,
+			u.ID, u.Name, u.AvatarURL, u.CreatedAt.UnixNano())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func forEachRepository(t *testing.T, fn func(t *testing.T, repo entity.UserRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, entity.NewMemoryUserRepository(fixtureUsers...))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, newSQLiteRepository(t, fixtureUsers...))
+	})
+}
+
+func TestUserRepository_Get(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo entity.UserRepository) {
+		u, err := repo.Get(context.Background(), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(u, fixtureUsers[1]) {
+			t.Fatalf("unexpected user %+v", u)
+		}
+	})
+}
+
+func TestUserRepository_GetUnknown(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo entity.UserRepository) {
+		_, err := repo.Get(context.Background(), "missing")
+		if !errors.Is(err, entity.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
+func TestUserRepository_ListOrderedByCreation(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo entity.UserRepository) {
+		users, err := repo.List(context.Background(), entity.UserListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []entity.User{fixtureUsers[1], fixtureUsers[0]}
+		if !reflect.DeepEqual(users, expected) {
+			t.Fatalf("unexpected users %+v", users)
+		}
+	})
+}
+
+func TestUserRepository_CreateUpdateDelete(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo entity.UserRepository) {
+		ctx := context.Background()
+
+		created, err := repo.Create(ctx, entity.User{Name: "Kit Alba"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.ID == "" || created.CreatedAt.IsZero() {
+			t.Fatalf("expected ID and CreatedAt to be assigned, got %+v", created)
+		}
+
+		created.Name = "Pablo Alba"
+		updated, err := repo.Update(ctx, created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(updated, created) {
+			t.Fatalf("unexpected updated user %+v", updated)
+		}
+
+		if err := repo.Delete(ctx, created.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Get(ctx, created.ID); !errors.Is(err, entity.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound after delete, got %v", err)
+		}
+	})
+}
+
+func TestUserRepository_UpdateDeleteUnknown(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo entity.UserRepository) {
+		ctx := context.Background()
+		if _, err := repo.Update(ctx, entity.User{ID: "missing", Name: "x"}); !errors.Is(err, entity.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound from Update, got %v", err)
+		}
+		if err := repo.Delete(ctx, "missing"); !errors.Is(err, entity.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound from Delete, got %v", err)
+		}
+	})
+}
+
+func pageIDs(page entity.UserPage) []string {
+	ids := []string{}
+	for _, u := range page.Users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestUserRepository_Page(t *testing.T) {
+	users := []entity.User{
+		{ID: "a", Name: "A", CreatedAt: time.Unix(1, 0).UTC()},
+		{ID: "b", Name: "B", CreatedAt: time.Unix(2, 0).UTC()},
+		{ID: "c", Name: "C", CreatedAt: time.Unix(2, 0).UTC()},
+		{ID: "d", Name: "D", CreatedAt: time.Unix(3, 0).UTC()},
+	}
+	after := entity.CursorOf(users[0])
+	before := entity.CursorOf(users[3])
+
+	tests := []struct {
+		name        string
+		query       entity.UserPageQuery
+		ids         []string
+		hasPrevious bool
+		hasNext     bool
+	}{
+		{"first", entity.UserPageQuery{First: 2}, []string{"a", "b"}, false, true},
+		{"first after", entity.UserPageQuery{First: 2, After: &after}, []string{"b", "c"}, true, true},
+		{"first after to end", entity.UserPageQuery{First: 5, After: &after}, []string{"b", "c", "d"}, true, false},
+		{"last", entity.UserPageQuery{Last: 2}, []string{"c", "d"}, true, false},
+		{"last before", entity.UserPageQuery{Last: 1, Before: &before}, []string{"c"}, true, true},
+		{"between", entity.UserPageQuery{First: 10, After: &after, Before: &before}, []string{"b", "c"}, true, true},
+	}
+
+	run := func(t *testing.T, repo entity.UserRepository) {
+		for _, tt := range tests {
+			page, err := repo.Page(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := pageIDs(page); !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.ids, ids)
+			}
+			if page.HasPrevious != tt.hasPrevious || page.HasNext != tt.hasNext {
+				t.Errorf("%s: expected previous=%v next=%v, got previous=%v next=%v",
+					tt.name, tt.hasPrevious, tt.hasNext, page.HasPrevious, page.HasNext)
+			}
+		}
+	}
+	t.Run("memory", func(t *testing.T) { run(t, entity.NewMemoryUserRepository(users...)) })
+	t.Run("sqlite", func(t *testing.T) { run(t, newSQLiteRepository(t, users...)) })
+}
+
+func TestUserRepository_ListOptions(t *testing.T) {
+	users := []entity.User{
+		{ID: "1", Name: "Carlos Alba", CreatedAt: time.Unix(1, 0).UTC()},
+		{ID: "2", Name: "Haley Levesque", CreatedAt: time.Unix(2, 0).UTC()},
+		{ID: "3", Name: "Kit Alba", CreatedAt: time.Unix(3, 0).UTC()},
+		{ID: "4", Name: "albert", CreatedAt: time.Unix(4, 0).UTC()},
+	}
+
+	tests := []struct {
+		name string
+		opts entity.UserListOptions
+		ids  []string
+	}{
+		{"name contains", entity.UserListOptions{NameContains: "ALBA"}, []string{"1", "3"}},
+		{"id in", entity.UserListOptions{IDs: []string{"4", "2"}}, []string{"2", "4"}},
+		{"empty id in", entity.UserListOptions{IDs: []string{}}, []string{}},
+		{"created after", entity.UserListOptions{CreatedAfter: time.Unix(2, 0)}, []string{"3", "4"}},
+		{"name asc", entity.UserListOptions{OrderBy: entity.OrderByNameAsc}, []string{"4", "1", "2", "3"}},
+		{"name desc", entity.UserListOptions{OrderBy: entity.OrderByNameDesc}, []string{"3", "2", "1", "4"}},
+		{"created desc", entity.UserListOptions{OrderBy: entity.OrderByCreatedAtDesc}, []string{"4", "3", "2", "1"}},
+		{"search ranks prefix", entity.UserListOptions{Search: "alb"}, []string{"4", "1", "3"}},
+		{"search with order", entity.UserListOptions{Search: "alb", OrderBy: entity.OrderByCreatedAtAsc}, []string{"1", "3", "4"}},
+	}
+
+	run := func(t *testing.T, repo entity.UserRepository) {
+		for _, tt := range tests {
+			got, err := repo.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, u := range got {
+				ids = append(ids, u.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.ids, ids)
+			}
+		}
+	}
+	t.Run("memory", func(t *testing.T) { run(t, entity.NewMemoryUserRepository(users...)) })
+	t.Run("sqlite", func(t *testing.T) { run(t, newSQLiteRepository(t, users...)) })
+}
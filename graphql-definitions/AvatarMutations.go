@@ -0,0 +1,127 @@
+package graphql_definitions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"strings"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/chalkedgoose/act-up-api/avatar"
+	"github.com/chalkedgoose/act-up-api/blob"
+	"github.com/graphql-go/graphql"
+)
+
+var errNoBlobStore = errors.New("uploads are not configured")
+
+// maxAvatarSize is the largest avatar accepted, in bytes.
+const maxAvatarSize = 5 << 20
+
+var UploadAvatarMutation = &graphql.Field{
+	Type:        UserType,
+	Description: "Replace the avatar of the authenticated user with an uploaded image",
+	Args: graphql.FieldConfigArgument{
+		"file": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(UploadScalar),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		principal := auth.PrincipalFromContext(p.Context)
+		if principal == nil {
+			return nil, auth.ErrUnauthenticated
+		}
+		store, ok := blob.FromContext(p.Context)
+		if !ok {
+			return nil, errNoBlobStore
+		}
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		file, ok := p.Args["file"].(*multipart.FileHeader)
+		if !ok {
+			return nil, apperrors.NewBadUserInput("file", "must be sent as a multipart upload")
+		}
+		if file.Size > maxAvatarSize {
+			return nil, apperrors.NewBadUserInput("file", fmt.Sprintf("must not be larger than %d bytes", maxAvatarSize))
+		}
+		data, err := readUpload(file)
+		if err != nil {
+			return nil, err
+		}
+		processed, err := avatar.Process(data)
+		if errors.Is(err, avatar.ErrUnsupportedFormat) || errors.Is(err, avatar.ErrTooManyPixels) {
+			return nil, apperrors.NewBadUserInput("file", err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := repo.Get(p.Context, principal.Subject)
+		if err != nil {
+			return nil, err
+		}
+
+		// content addressed, so the media route can let clients cache forever
+		sum := sha256.Sum256(processed.Variants[avatar.Sizes[len(avatar.Sizes)-1]])
+		dir := fmt.Sprintf("avatars/%s/%s", user.ID, hex.EncodeToString(sum[:8]))
+		var keys []string
+		for _, size := range avatar.Sizes {
+			key := avatar.Key(dir, size, processed.Ext)
+			if err := store.Put(p.Context, key, bytes.NewReader(processed.Variants[size])); err != nil {
+				deleteBlobs(p, store, keys)
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+
+		previous := user.AvatarURL
+		user.AvatarURL = store.URL(keys[len(keys)-1])
+		user, err = repo.Update(p.Context, user)
+		if err != nil {
+			deleteBlobs(p, store, keys)
+			return nil, err
+		}
+		if previousKey := strings.TrimPrefix(previous, store.URL("")); previousKey != previous && previous != user.AvatarURL {
+			deleteBlobs(p, store, avatarKeys(previousKey))
+		}
+		publish(p, TopicUserUpdated, user)
+		return user, nil
+	},
+}
+
+// readUpload returns the contents of file.
+func readUpload(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(io.LimitReader(f, maxAvatarSize))
+}
+
+// avatarKeys returns every variant stored alongside the avatar blob key.
+func avatarKeys(key string) []string {
+	if _, ok := avatar.VariantKey(key, avatar.Sizes[0]); !ok {
+		return []string{key}
+	}
+	keys := make([]string, len(avatar.Sizes))
+	for i, size := range avatar.Sizes {
+		keys[i], _ = avatar.VariantKey(key, size)
+	}
+	return keys
+}
+
+// deleteBlobs removes keys on a best effort basis; a leftover blob is only wasted space.
+func deleteBlobs(p graphql.ResolveParams, store blob.Store, keys []string) {
+	for _, key := range keys {
+		store.Delete(p.Context, key)
+	}
+}
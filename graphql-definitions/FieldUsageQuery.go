@@ -0,0 +1,115 @@
+package graphql_definitions
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/graphql-go/graphql"
+)
+
+var errNoUsageStore = errors.New("field usage is not recorded")
+
+// maxFieldUsageDays bounds the window of fieldUsage to the usage that is kept.
+const maxFieldUsageDays = usage.RetentionDays
+
+var FieldUsageType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "FieldUsage",
+	Description: "How many operations selected a field of the schema",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(usage.FieldCount).Field.Type, nil
+			},
+		},
+		"field": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(usage.FieldCount).Field.Name, nil
+			},
+		},
+		"count": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(usage.FieldCount).Count, nil
+			},
+		},
+		"operations": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "The named operations that selected the field",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(usage.FieldCount).Operations, nil
+			},
+		},
+		"clients": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "The clients that selected the field, as named by their apollographql-client-name header",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(usage.FieldCount).Clients, nil
+			},
+		},
+		"lastUsed": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "The start of the last hour the field was selected in, null when unused",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				lastUsed := p.Source.(usage.FieldCount).LastUsed
+				if lastUsed.IsZero() {
+					return nil, nil
+				}
+				return lastUsed, nil
+			},
+		},
+	},
+})
+
+var GetFieldUsageQuery = auth.Protect(auth.RequireRole(auth.RoleAdmin), &graphql.Field{
+	Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(FieldUsageType))),
+	Description: "How often each field of the schema was selected over the last days, most used first",
+	Args: graphql.FieldConfigArgument{
+		"days": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: 30,
+			Description:  "How many days back to report, at most " + strconv.Itoa(maxFieldUsageDays),
+		},
+		"type": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Only report the fields of this type",
+		},
+		"unused": &graphql.ArgumentConfig{
+			Type:         graphql.Boolean,
+			DefaultValue: false,
+			Description:  "Only report the fields no operation selected",
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		store, ok := usage.FromContext(p.Context)
+		if !ok {
+			return nil, errNoUsageStore
+		}
+		days, _ := p.Args["days"].(int)
+		if days <= 0 || days > maxFieldUsageDays {
+			return nil, apperrors.NewBadUserInput("days", "must be between 1 and "+strconv.Itoa(maxFieldUsageDays))
+		}
+
+		until := time.Now()
+		usages, err := store.List(p.Context, until.Add(-time.Duration(days)*24*time.Hour), until)
+		if err != nil {
+			return nil, err
+		}
+		report := usage.Report(&p.Info.Schema, usage.Summarize(usages))
+
+		typeName, _ := p.Args["type"].(string)
+		unused, _ := p.Args["unused"].(bool)
+		filtered := make([]usage.FieldCount, 0, len(report))
+		for _, count := range report {
+			if (typeName == "" || count.Field.Type == typeName) && (!unused || count.Count == 0) {
+				filtered = append(filtered, count)
+			}
+		}
+		return filtered, nil
+	},
+})
@@ -1,56 +1,133 @@
 package graphql_definitions
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/chalkedgoose/act-up-api/entity"
 	"github.com/graphql-go/graphql"
 )
 
+var errNoUserRepository = errors.New("user repository is not configured")
+
+// userRepository returns the store attached to the request context by the server.
+func userRepository(p graphql.ResolveParams) (entity.UserRepository, error) {
+	repo, ok := entity.UserRepositoryFromContext(p.Context)
+	if !ok {
+		return nil, errNoUserRepository
+	}
+	return repo, nil
+}
+
 var GetUserQuery = &graphql.Field{
 	Type:        UserType,
 	Description: "Get a single user",
 	Args: graphql.FieldConfigArgument{
 		"id": &graphql.ArgumentConfig{
-			Type: graphql.ID,
+			Type: graphql.NewNonNull(graphql.ID),
 		},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		id := p.Args["id"].(string)
-		return entity.User{
-			ID:        id,
-			Name:      "Carlos Alba",
-			AvatarURL: "https://picsum.photos/350",
-		}, nil
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := decodeUserID("id", p.Args["id"].(string))
+		if err != nil {
+			return nil, err
+		}
+		user, err := repo.Get(p.Context, id)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
 	},
 }
 
+var UserFilterInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"nameContains": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Case-insensitive substring of the user's name",
+		},
+		"idIn": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewList(graphql.NewNonNull(graphql.ID)),
+		},
+		"createdAfter": &graphql.InputObjectFieldConfig{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+var UserOrderByEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "UserOrderBy",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED_AT_ASC": &graphql.EnumValueConfig{
+			Value: entity.OrderByCreatedAtAsc,
+		},
+		"CREATED_AT_DESC": &graphql.EnumValueConfig{
+			Value: entity.OrderByCreatedAtDesc,
+		},
+		"NAME_ASC": &graphql.EnumValueConfig{
+			Value: entity.OrderByNameAsc,
+		},
+		"NAME_DESC": &graphql.EnumValueConfig{
+			Value: entity.OrderByNameDesc,
+		},
+	},
+})
+
 var GetUsersQuery = &graphql.Field{
 	Type:        graphql.NewList(UserType),
 	Description: "List of users",
+	Args: graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{
+			Type: UserFilterInputType,
+		},
+		"orderBy": &graphql.ArgumentConfig{
+			Type:        UserOrderByEnum,
+			Description: "Defaults to creation order, or to best match when searching",
+		},
+		"search": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Case-insensitive text to find in names, ranking prefix matches first",
+		},
+	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
 
-		users := []entity.User{
-			entity.User{
-				ID:        "1",
-				Name:      "Carlos Alba",
-				AvatarURL: "https://picsum.photos/350",
-			},
-			entity.User{
-				ID:        "2",
-				Name:      "Haley Levesque",
-				AvatarURL: "https://picsum.photos/350",
-			},
-			entity.User{
-				ID:        "3",
-				Name:      "Kit Alba",
-				AvatarURL: "https://picsum.photos/350",
-			},
-			entity.User{
-				ID:        "4",
-				Name:      "Pablo Alba",
-				AvatarURL: "https://picsum.photos/350",
-			},
+		opts := entity.UserListOptions{}
+		if search, ok := p.Args["search"].(string); ok {
+			opts.Search = strings.TrimSpace(search)
+		}
+		if orderBy, ok := p.Args["orderBy"].(entity.UserOrder); ok {
+			opts.OrderBy = orderBy
+		}
+		if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+			if nameContains, ok := filter["nameContains"].(string); ok {
+				opts.NameContains = nameContains
+			}
+			if createdAfter, ok := filter["createdAfter"].(time.Time); ok {
+				opts.CreatedAfter = createdAfter
+			}
+			if idIn, ok := filter["idIn"].([]interface{}); ok {
+				opts.IDs = make([]string, 0, len(idIn))
+				for _, globalID := range idIn {
+					id, err := decodeUserID("idIn", globalID.(string))
+					if err != nil {
+						return nil, err
+					}
+					opts.IDs = append(opts.IDs, id)
+				}
+			}
 		}
 
-		return users, nil
+		return repo.List(p.Context, opts)
 	},
 }
@@ -0,0 +1,123 @@
+package graphql_definitions
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/graphql-go/graphql"
+)
+
+// Global IDs are the base64 encoding of "<TypeName>:<storage id>", so any object
+// can be refetched through `node` without the client knowing its type.
+
+var NodeInterface = graphql.NewInterface(graphql.InterfaceConfig{
+	Name:        "Node",
+	Description: "An object with a globally unique ID",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	},
+})
+
+// nodeLoader fetches the object of one type by its storage ID, returning nil when it does not exist.
+type nodeLoader func(p graphql.ResolveParams, id string) (interface{}, error)
+
+var nodeLoaders = map[string]nodeLoader{
+	"User": loadUserNode,
+}
+
+func toGlobalID(typeName, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+func fromGlobalID(globalID string) (typeName, id string, err error) {
+	raw, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", err
+	}
+	typeName, id, found := strings.Cut(string(raw), ":")
+	if !found || typeName == "" || id == "" {
+		return "", "", errors.New("malformed global ID")
+	}
+	return typeName, id, nil
+}
+
+// decodeUserID returns the storage ID of a User global ID passed in the named argument.
+func decodeUserID(field, globalID string) (string, error) {
+	typeName, id, err := fromGlobalID(globalID)
+	if err != nil || typeName != "User" {
+		return "", apperrors.NewBadUserInput(field, "is not a User ID")
+	}
+	return id, nil
+}
+
+func loadUserNode(p graphql.ResolveParams, id string) (interface{}, error) {
+	repo, err := userRepository(p)
+	if err != nil {
+		return nil, err
+	}
+	user, err := repo.Get(p.Context, id)
+	if errors.Is(err, entity.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func resolveNode(p graphql.ResolveParams, field, globalID string) (interface{}, error) {
+	typeName, id, err := fromGlobalID(globalID)
+	if err != nil {
+		return nil, apperrors.NewBadUserInput(field, "is not a valid ID")
+	}
+	load, ok := nodeLoaders[typeName]
+	if !ok {
+		return nil, nil
+	}
+	return load(p, id)
+}
+
+var GetNodeQuery = &graphql.Field{
+	Type:        NodeInterface,
+	Description: "Fetch any object by its global ID",
+	Args: graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return resolveNode(p, "id", p.Args["id"].(string))
+	},
+}
+
+var GetNodesQuery = &graphql.Field{
+	Type:        graphql.NewNonNull(graphql.NewList(NodeInterface)),
+	Description: "Fetch objects by global ID, in the order requested; unknown IDs resolve to null",
+	Args: graphql.FieldConfigArgument{
+		"ids": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		ids := p.Args["ids"].([]interface{})
+		if len(ids) > maxPageSize {
+			return nil, apperrors.NewBadUserInput("ids", "must not contain more than "+strconv.Itoa(maxPageSize)+" IDs")
+		}
+
+		nodes := make([]interface{}, len(ids))
+		for i, id := range ids {
+			node, err := resolveNode(p, "ids", id.(string))
+			if err != nil {
+				return nil, err
+			}
+			nodes[i] = node
+		}
+		return nodes, nil
+	},
+}
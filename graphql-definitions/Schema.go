@@ -5,10 +5,43 @@ import (
 )
 
 var fields = graphql.Fields{
-	"user":  GetUserQuery,
-	"users": GetUsersQuery,
+	"fieldUsage":      GetFieldUsageQuery,
+	"node":            GetNodeQuery,
+	"nodes":           GetNodesQuery,
+	"user":            GetUserQuery,
+	"users":           GetUsersQuery,
+	"usersConnection": GetUsersConnectionQuery,
+	"viewer":          GetViewerQuery,
+}
+
+var mutationFields = graphql.Fields{
+	"createUser":   CreateUserMutation,
+	"updateUser":   UpdateUserMutation,
+	"deleteUser":   DeleteUserMutation,
+	"uploadAvatar": UploadAvatarMutation,
+}
+
+var subscriptionFields = graphql.Fields{
+	"userCreated": UserCreatedSubscription,
+	"userUpdated": UserUpdatedSubscription,
 }
 
 var rootQuery = graphql.ObjectConfig{Name: "RootQuery", Fields: fields}
 
-var AppSchemaConfig = graphql.SchemaConfig{Query: graphql.NewObject(rootQuery)}
+var rootMutation = graphql.ObjectConfig{Name: "RootMutation", Fields: mutationFields}
+
+var rootSubscription = graphql.ObjectConfig{Name: "RootSubscription", Fields: subscriptionFields}
+
+var AppSchemaConfig = graphql.SchemaConfig{
+	Query:        graphql.NewObject(rootQuery),
+	Mutation:     graphql.NewObject(rootMutation),
+	Subscription: graphql.NewObject(rootSubscription),
+}
+
+// FieldCosts are complexity hints for the handler's MaxComplexity check,
+// keyed by "Type.field". Fields not listed cost 1.
+var FieldCosts = map[string]int{
+	"RootQuery.users":           10,
+	"RootQuery.usersConnection": 2,
+	"RootQuery.nodes":           5,
+}
@@ -0,0 +1,536 @@
+package graphql_definitions_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/chalkedgoose/act-up-api/blob"
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/chalkedgoose/act-up-api/graphql-definitions"
+	"github.com/chalkedgoose/act-up-api/pubsub"
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/graphql-go/graphql"
+)
+
+var admin = &auth.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}}
+
+func execute(t *testing.T, repo entity.UserRepository, query string) string {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: query,
+		Context:       auth.WithPrincipal(entity.WithUserRepository(context.Background(), repo), admin),
+	})
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUserQuery(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "7", Name: "Kit Alba", AvatarURL: "https://picsum.photos/350"})
+
+	got := execute(t, repo, `{ user(id: "VXNlcjo3") { id name avatarURL } }`)
+	expected := `{"data":{"user":{"avatarURL":"https://picsum.photos/350","id":"VXNlcjo3","name":"Kit Alba"}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestUserQuery_NotFound(t *testing.T) {
+	got := execute(t, entity.NewMemoryUserRepository(), `{ user(id: "VXNlcjox") { id } }`)
+	expected := `{"data":{"user":null},"errors":[{"message":"user not found","locations":[{"line":1,"column":3}],"path":["user"],"extensions":{"code":"NOT_FOUND"}}]}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestCreateUserMutation(t *testing.T) {
+	repo := entity.NewMemoryUserRepository()
+
+	got := execute(t, repo, `mutation { createUser(input: {name: "  Haley Levesque ", avatarURL: "https://picsum.photos/350"}) { name avatarURL } }`)
+	expected := `{"data":{"createUser":{"avatarURL":"https://picsum.photos/350","name":"Haley Levesque"}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	users, _ := repo.List(context.Background(), entity.UserListOptions{})
+	if len(users) != 1 || users[0].Name != "Haley Levesque" {
+		t.Fatalf("user was not stored: %+v", users)
+	}
+}
+
+func TestCreateUserMutation_Invalid(t *testing.T) {
+	got := execute(t, entity.NewMemoryUserRepository(), `mutation { createUser(input: {name: "Kit", avatarURL: "ftp://example.com/a.png"}) { id } }`)
+	expected := `{"data":{"createUser":null},"errors":[{"message":"invalid avatarURL: must be an absolute http or https URL","locations":[{"line":1,"column":12}],"path":["createUser"],"extensions":{"code":"BAD_USER_INPUT","field":"avatarURL"}}]}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestUpdateAndDeleteUserMutation(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "1", Name: "Carlos Alba"})
+
+	got := execute(t, repo, `mutation { updateUser(input: {id: "VXNlcjox", avatarURL: "https://picsum.photos/128"}) { name avatarURL } }`)
+	expected := `{"data":{"updateUser":{"avatarURL":"https://picsum.photos/128","name":"Carlos Alba"}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	got = execute(t, repo, `mutation { deleteUser(id: "VXNlcjox") { id } }`)
+	expected = `{"data":{"deleteUser":{"id":"VXNlcjox"}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	if _, err := repo.Get(context.Background(), "1"); err != entity.ErrUserNotFound {
+		t.Fatalf("expected user to be deleted, got %v", err)
+	}
+}
+
+func TestUsersConnectionQuery(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(
+		entity.User{ID: "1", Name: "Carlos Alba", CreatedAt: time.Unix(1, 0)},
+		entity.User{ID: "2", Name: "Haley Levesque", CreatedAt: time.Unix(2, 0)},
+		entity.User{ID: "3", Name: "Kit Alba", CreatedAt: time.Unix(3, 0)},
+	)
+
+	got := execute(t, repo, `{ usersConnection(first: 2) { edges { node { name } } pageInfo { hasNextPage endCursor } } }`)
+	var first struct {
+		Data struct {
+			UsersConnection struct {
+				Edges []struct {
+					Node struct{ Name string }
+				}
+				PageInfo struct {
+					HasNextPage bool
+					EndCursor   string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(got), &first); err != nil {
+		t.Fatal(err)
+	}
+	conn := first.Data.UsersConnection
+	if len(conn.Edges) != 2 || conn.Edges[1].Node.Name != "Haley Levesque" || !conn.PageInfo.HasNextPage {
+		t.Fatalf("unexpected first page: %s", got)
+	}
+
+	got = execute(t, repo, `{ usersConnection(first: 2, after: "`+conn.PageInfo.EndCursor+`") { edges { node { name } } pageInfo { hasNextPage hasPreviousPage } } }`)
+	expected := `{"data":{"usersConnection":{"edges":[{"node":{"name":"Kit Alba"}}],"pageInfo":{"hasNextPage":false,"hasPreviousPage":true}}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestUsersConnectionQuery_InvalidCursor(t *testing.T) {
+	got := execute(t, entity.NewMemoryUserRepository(), `{ usersConnection(after: "nope") { pageInfo { hasNextPage } } }`)
+	expected := `{"data":null,"errors":[{"message":"invalid after: is not a valid cursor","locations":[{"line":1,"column":3}],"path":["usersConnection"],"extensions":{"code":"BAD_USER_INPUT","field":"after"}}]}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestUsersQuery_FilterAndOrder(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(
+		entity.User{ID: "1", Name: "Carlos Alba", CreatedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		entity.User{ID: "2", Name: "Haley Levesque", CreatedAt: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)},
+		entity.User{ID: "3", Name: "Kit Alba", CreatedAt: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+	)
+
+	got := execute(t, repo, `{ users(filter: {createdAfter: "2022-01-15T00:00:00Z"}, orderBy: NAME_DESC) { name createdAt } }`)
+	expected := `{"data":{"users":[{"createdAt":"2022-03-01T00:00:00Z","name":"Kit Alba"},{"createdAt":"2022-02-01T00:00:00Z","name":"Haley Levesque"}]}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	got = execute(t, repo, `{ users(search: "ALBA", filter: {idIn: ["VXNlcjoz", "VXNlcjoy"]}) { id } }`)
+	expected = `{"data":{"users":[{"id":"VXNlcjoz"}]}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestNodeQueries(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(
+		entity.User{ID: "1", Name: "Carlos Alba"},
+		entity.User{ID: "2", Name: "Haley Levesque"},
+	)
+
+	got := execute(t, repo, `{ node(id: "VXNlcjoy") { id ... on User { name } } }`)
+	expected := `{"data":{"node":{"id":"VXNlcjoy","name":"Haley Levesque"}}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	// unknown users and unknown types both resolve to null
+	got = execute(t, repo, `{ nodes(ids: ["VXNlcjox", "VXNlcjo3", "UG9zdDox"]) { id __typename } }`)
+	expected = `{"data":{"nodes":[{"__typename":"User","id":"VXNlcjox"},null,null]}}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+
+	got = execute(t, repo, `{ node(id: "not-an-id") { id } }`)
+	expected = `{"data":{"node":null},"errors":[{"message":"invalid id: is not a valid ID","locations":[{"line":1,"column":3}],"path":["node"],"extensions":{"code":"BAD_USER_INPUT","field":"id"}}]}`
+	if got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestViewerQuery(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "2", Name: "Haley Levesque"})
+
+	ctx := entity.WithUserRepository(context.Background(), repo)
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ viewer { name } }`, Context: ctx})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("expected UNAUTHENTICATED error, got %+v", result.Errors)
+	}
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "2"})
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: `{ viewer { name } }`, Context: ctx})
+	b, _ := json.Marshal(result)
+	if expected := `{"data":{"viewer":{"name":"Haley Levesque"}}}`; string(b) != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", b, expected)
+	}
+}
+
+func TestMutationsRequireAdmin(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository()
+	ctx := entity.WithUserRepository(context.Background(), repo)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "2", Roles: []string{"MEMBER"}})
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `mutation { createUser(input: {name: "Kit Alba"}) { id } }`,
+		Context:       ctx,
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN error, got %+v", result.Errors)
+	}
+	if users, _ := repo.List(context.Background(), entity.UserListOptions{}); len(users) != 0 {
+		t.Fatalf("mutation should not have run, stored %+v", users)
+	}
+}
+
+func TestUserSubscriptions(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "1", Name: "Carlos Alba"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = pubsub.WithPubSub(entity.WithUserRepository(ctx, repo), pubsub.New())
+	ctx = auth.WithPrincipal(ctx, admin)
+
+	created := graphql.Subscribe(graphql.Params{Schema: schema, RequestString: `subscription { userCreated { name } }`, Context: ctx})
+	updated := graphql.Subscribe(graphql.Params{Schema: schema, RequestString: `subscription { userUpdated(id: "VXNlcjox") { name } }`, Context: ctx})
+	// give both subscriptions time to register with the PubSub
+	time.Sleep(50 * time.Millisecond)
+
+	graphql.Do(graphql.Params{Schema: schema, RequestString: `mutation { createUser(input: {name: "Kit Alba"}) { id } }`, Context: ctx})
+	graphql.Do(graphql.Params{Schema: schema, RequestString: `mutation { updateUser(input: {id: "VXNlcjox", name: "Pablo Alba"}) { id } }`, Context: ctx})
+
+	for ch, expected := range map[chan *graphql.Result]string{
+		created: `{"data":{"userCreated":{"name":"Kit Alba"}}}`,
+		updated: `{"data":{"userUpdated":{"name":"Pablo Alba"}}}`,
+	} {
+		select {
+		case result := <-ch:
+			b, _ := json.Marshal(result)
+			if string(b) != expected {
+				t.Fatalf("unexpected event\n got: %s\nwant: %s", b, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event received, expected %s", expected)
+		}
+	}
+
+	// drain the result channels so graphql-go's goroutines can exit
+	cancel()
+	for range created {
+	}
+	for range updated {
+	}
+}
+
+// uploadedFile returns contents as the handler would pass a multipart file part.
+func uploadedFile(t *testing.T, contentType, contents string) *multipart.FileHeader {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="0"; filename="avatar"`)
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(contents))
+	w.Close()
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["0"][0]
+}
+
+// pngOf returns a w×h PNG filled with c.
+func pngOf(t *testing.T, w, h int, c color.Color) string {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestUploadAvatarMutation(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const baseURL = "http://localhost:8080/media/"
+	store, err := blob.NewFileStore(t.TempDir(), baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "2", Name: "Haley Levesque"})
+	ctx := blob.WithStore(entity.WithUserRepository(context.Background(), repo), store)
+	upload := func(ctx context.Context, file *multipart.FileHeader) *graphql.Result {
+		return graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  `mutation ($file: Upload!) { uploadAvatar(file: $file) { avatarURL small: avatar(size: 100) } }`,
+			VariableValues: map[string]interface{}{"file": file},
+			Context:        ctx,
+		})
+	}
+
+	result := upload(ctx, uploadedFile(t, "image/png", pngOf(t, 10, 10, color.White)))
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("expected UNAUTHENTICATED error, got %+v", result.Errors)
+	}
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "2"})
+	// the declared Content-Type is not trusted, the contents are sniffed
+	result = upload(ctx, uploadedFile(t, "image/png", "<html><script>alert(1)</script></html>"))
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+		t.Fatalf("expected BAD_USER_INPUT error, got %+v", result.Errors)
+	}
+
+	var dirs []string
+	for _, c := range []color.Color{color.White, color.Black} {
+		result = upload(ctx, uploadedFile(t, "application/octet-stream", pngOf(t, 400, 300, c)))
+		if len(result.Errors) != 0 {
+			t.Fatalf("unexpected errors %+v", result.Errors)
+		}
+		data := result.Data.(map[string]interface{})["uploadAvatar"].(map[string]interface{})
+		url := data["avatarURL"].(string)
+		if !strings.HasPrefix(url, baseURL+"avatars/2/") || !strings.HasSuffix(url, "/350.png") {
+			t.Fatalf("unexpected avatar URL %s", url)
+		}
+		dir := strings.TrimSuffix(strings.TrimPrefix(url, baseURL), "/350.png")
+		if small := data["small"].(string); small != baseURL+dir+"/128.png" {
+			t.Fatalf("unexpected small avatar URL %s", small)
+		}
+		for _, size := range []string{"64", "128", "350"} {
+			object, err := store.Open(context.Background(), dir+"/"+size+".png")
+			if err != nil {
+				t.Fatalf("variant %s: %v", size, err)
+			}
+			config, _, err := image.DecodeConfig(object)
+			object.Close()
+			if err != nil || fmt.Sprint(config.Width) != size || config.Height != config.Width {
+				t.Fatalf("variant %s is %dx%d (%v)", size, config.Width, config.Height, err)
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+
+	user, _ := repo.Get(context.Background(), "2")
+	if user.AvatarURL != baseURL+dirs[1]+"/350.png" {
+		t.Fatalf("expected the user to have the second avatar, got %s", user.AvatarURL)
+	}
+	for _, size := range []string{"64", "128", "350"} {
+		if _, err := store.Open(context.Background(), dirs[0]+"/"+size+".png"); err != blob.ErrNotFound {
+			t.Fatalf("expected the replaced avatar to be deleted, got %v", err)
+		}
+	}
+}
+
+func TestAvatarField_ExternalURL(t *testing.T) {
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "7", Name: "Kit Alba", AvatarURL: "https://picsum.photos/350"})
+
+	got := execute(t, repo, `{ user(id: "VXNlcjo3") { avatar(size: 64) } }`)
+	if expected := `{"data":{"user":{"avatar":"https://picsum.photos/350"}}}`; got != expected {
+		t.Fatalf("unexpected result\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestAvatarField_SignedURLs(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const baseURL = "http://localhost:8080/media/"
+	store, err := blob.NewFileStore(t.TempDir(), baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "7", Name: "Kit Alba", AvatarURL: baseURL + "avatars/7/ab/350.png"})
+	ctx := blob.WithStore(entity.WithUserRepository(context.Background(), repo), store)
+	ctx = blob.WithSigner(ctx, blob.NewSigner([]byte("secret"), time.Hour))
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ user(id: "VXNlcjo3") { avatarURL avatar(size: 64) } }`,
+		Context:       ctx,
+	})
+	if len(result.Errors) != 0 {
+		t.Fatalf("unexpected errors %+v", result.Errors)
+	}
+	user := result.Data.(map[string]interface{})["user"].(map[string]interface{})
+	if url := user["avatarURL"].(string); !strings.HasPrefix(url, baseURL+"avatars/7/ab/350.png?expires=") {
+		t.Fatalf("expected a signed avatarURL, got %s", url)
+	}
+	if url := user["avatar"].(string); !strings.HasPrefix(url, baseURL+"avatars/7/ab/64.png?expires=") {
+		t.Fatalf("expected a signed avatar variant, got %s", url)
+	}
+
+	// what is stored stays unsigned
+	if stored, _ := repo.Get(context.Background(), "7"); stored.AvatarURL != baseURL+"avatars/7/ab/350.png" {
+		t.Fatalf("unexpected stored avatar URL %s", stored.AvatarURL)
+	}
+}
+
+func TestUploadAvatarMutation_SameImageTwice(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := blob.NewFileStore(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := entity.NewMemoryUserRepository(entity.User{ID: "2", Name: "Haley Levesque"})
+	ctx := blob.WithStore(entity.WithUserRepository(context.Background(), repo), store)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "2"})
+
+	image := pngOf(t, 50, 50, color.White)
+	for i := 0; i < 2; i++ {
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  `mutation ($file: Upload!) { uploadAvatar(file: $file) { avatarURL } }`,
+			VariableValues: map[string]interface{}{"file": uploadedFile(t, "image/png", image)},
+			Context:        ctx,
+		})
+		if len(result.Errors) != 0 {
+			t.Fatalf("unexpected errors %+v", result.Errors)
+		}
+	}
+
+	user, _ := repo.Get(context.Background(), "2")
+	object, err := store.Open(context.Background(), strings.TrimPrefix(user.AvatarURL, "/media/"))
+	if err != nil {
+		t.Fatalf("expected the re-uploaded avatar to be kept, got %v", err)
+	}
+	object.Close()
+}
+
+func TestFieldUsageQuery(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql_definitions.AppSchemaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := usage.NewMemoryStore()
+	hour := time.Now().UTC().Truncate(time.Hour)
+	err = store.Add(context.Background(), []usage.Usage{
+		{Hour: hour, Field: usage.Field{Type: "User", Name: "name"}, OperationName: "Profile", ClientName: "web", Count: 5},
+		{Hour: hour, Field: usage.Field{Type: "User", Name: "id"}, OperationName: "Profile", ClientName: "ios", Count: 2},
+		{Hour: hour.Add(-60 * 24 * time.Hour), Field: usage.Field{Type: "User", Name: "avatar"}, Count: 9},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := auth.WithPrincipal(usage.WithStore(context.Background(), store), admin)
+
+	type fieldUsage struct {
+		Field    string   `json:"field"`
+		Count    int      `json:"count"`
+		Clients  []string `json:"clients"`
+		LastUsed *string  `json:"lastUsed"`
+	}
+	report := func(query string) []fieldUsage {
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: ctx})
+		if len(result.Errors) != 0 {
+			t.Fatalf("unexpected errors %+v", result.Errors)
+		}
+		b, _ := json.Marshal(result.Data)
+		var data struct {
+			FieldUsage []fieldUsage `json:"fieldUsage"`
+		}
+		json.Unmarshal(b, &data)
+		return data.FieldUsage
+	}
+
+	fields := report(`{ fieldUsage(type: "User") { field count clients lastUsed } }`)
+	if len(fields) < 4 || fields[0].Field != "name" || fields[0].Count != 5 || fields[0].Clients[0] != "web" ||
+		fields[1].Field != "id" || fields[1].Count != 2 || fields[0].LastUsed == nil {
+		t.Fatalf("unexpected report %+v", fields)
+	}
+	for _, field := range fields[2:] {
+		if field.Count != 0 || field.LastUsed != nil || len(field.Clients) != 0 {
+			t.Fatalf("usage outside of the window was reported: %+v", field)
+		}
+	}
+
+	unused := report(`{ fieldUsage(type: "User", unused: true) { field count } }`)
+	if len(unused) != len(fields)-2 {
+		t.Fatalf("unexpected unused fields %+v", unused)
+	}
+	if all := report(`{ fieldUsage(days: 90, type: "User") { field count } }`); all[0].Field != "avatar" {
+		t.Fatalf("unexpected report over 90 days %+v", all)
+	}
+
+	var result *graphql.Result
+	for _, days := range []string{"0", "367", "1000000"} {
+		result = graphql.Do(graphql.Params{Schema: schema, RequestString: `{ fieldUsage(days: ` + days + `) { field } }`, Context: ctx})
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+			t.Fatalf("days: %s: expected BAD_USER_INPUT error, got %+v", days, result.Errors)
+		}
+	}
+	result = graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ fieldUsage { field } }`,
+		Context:       auth.WithPrincipal(usage.WithStore(context.Background(), store), &auth.Principal{Subject: "2"}),
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN error, got %+v", result.Errors)
+	}
+}
@@ -0,0 +1,28 @@
+package graphql_definitions
+
+import (
+	"mime/multipart"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// UploadScalar is a file sent with the GraphQL multipart request spec. The
+// handler places each file into the variables as a *multipart.FileHeader; it
+// cannot be written inline in a query nor be returned.
+var UploadScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Upload",
+	Description: "A file uploaded as part of a multipart request",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if file, ok := value.(*multipart.FileHeader); ok {
+			return file
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
@@ -1,18 +1,98 @@
 package graphql_definitions
 
-import "github.com/graphql-go/graphql"
+import (
+	"strings"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/avatar"
+	"github.com/chalkedgoose/act-up-api/blob"
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/graphql-go/graphql"
+)
 
 var UserType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "User",
+	Name:       "User",
+	Interfaces: []*graphql.Interface{NodeInterface},
+	IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+		_, ok := p.Value.(entity.User)
+		return ok
+	},
 	Fields: graphql.Fields{
 		"id": &graphql.Field{
-			Type: graphql.ID,
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return toGlobalID("User", p.Source.(entity.User).ID), nil
+			},
 		},
 		"name": &graphql.Field{
 			Type: graphql.String,
 		},
 		"avatarURL": &graphql.Field{
-			Type: graphql.Boolean,
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return mediaURL(p, p.Source.(entity.User).AvatarURL), nil
+			},
+		},
+		"avatar": &graphql.Field{
+			Type:        graphql.String,
+			Description: "URL of the uploaded avatar variant closest to `size` pixels, or of the largest one",
+			Args: graphql.FieldConfigArgument{
+				"size": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+			},
+			Resolve: resolveAvatar,
+		},
+		"createdAt": &graphql.Field{
+			Type: graphql.DateTime,
 		},
 	},
 })
+
+// resolveAvatar picks the square variant for the requested size. Avatars that
+// were not uploaded through uploadAvatar have no variants and resolve to AvatarURL.
+func resolveAvatar(p graphql.ResolveParams) (interface{}, error) {
+	user := p.Source.(entity.User)
+	if user.AvatarURL == "" {
+		return nil, nil
+	}
+	size := avatar.Sizes[len(avatar.Sizes)-1]
+	if requested, ok := p.Args["size"].(int); ok {
+		if requested <= 0 {
+			return nil, apperrors.NewBadUserInput("size", "must be positive")
+		}
+		size = avatar.Closest(requested)
+	}
+
+	store, ok := blob.FromContext(p.Context)
+	if !ok {
+		return user.AvatarURL, nil
+	}
+	key := strings.TrimPrefix(user.AvatarURL, store.URL(""))
+	if key == user.AvatarURL {
+		return user.AvatarURL, nil
+	}
+	variant, ok := avatar.VariantKey(key, size)
+	if !ok {
+		return mediaURL(p, user.AvatarURL), nil
+	}
+	return mediaURL(p, store.URL(variant)), nil
+}
+
+// mediaURL returns the URL clients get for url. Blobs of the store are signed
+// when the media route only serves signed URLs; other URLs are left alone.
+func mediaURL(p graphql.ResolveParams, url string) string {
+	store, ok := blob.FromContext(p.Context)
+	if !ok {
+		return url
+	}
+	signer, ok := blob.SignerFromContext(p.Context)
+	if !ok {
+		return url
+	}
+	key := strings.TrimPrefix(url, store.URL(""))
+	if key == url {
+		return url
+	}
+	return signer.URL(store, key)
+}
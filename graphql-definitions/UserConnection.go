@@ -0,0 +1,190 @@
+package graphql_definitions
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var PageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"hasPreviousPage": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+		},
+		"startCursor": &graphql.Field{
+			Type: graphql.String,
+		},
+		"endCursor": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var UserEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"node": &graphql.Field{
+			Type: graphql.NewNonNull(UserType),
+		},
+	},
+})
+
+var UserConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserConnection",
+	Fields: graphql.Fields{
+		"edges": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(UserEdgeType))),
+		},
+		"pageInfo": &graphql.Field{
+			Type: graphql.NewNonNull(PageInfoType),
+		},
+	},
+})
+
+type pageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+type userEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   entity.User `json:"node"`
+}
+
+type userConnection struct {
+	Edges    []userEdge `json:"edges"`
+	PageInfo pageInfo   `json:"pageInfo"`
+}
+
+var GetUsersConnectionQuery = &graphql.Field{
+	Type:        graphql.NewNonNull(UserConnectionType),
+	Description: "Page through users in creation order",
+	Args: graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type: graphql.Int,
+		},
+		"after": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"last": &graphql.ArgumentConfig{
+			Type: graphql.Int,
+		},
+		"before": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := userPageQuery(p.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := repo.Page(p.Context, q)
+		if err != nil {
+			return nil, err
+		}
+
+		conn := userConnection{
+			Edges: make([]userEdge, len(page.Users)),
+			PageInfo: pageInfo{
+				HasNextPage:     page.HasNext,
+				HasPreviousPage: page.HasPrevious,
+			},
+		}
+		for i, u := range page.Users {
+			conn.Edges[i] = userEdge{Cursor: encodeUserCursor(entity.CursorOf(u)), Node: u}
+		}
+		if len(conn.Edges) > 0 {
+			conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+			conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+		}
+		return conn, nil
+	},
+}
+
+// userPageQuery validates the Relay connection arguments.
+func userPageQuery(args map[string]interface{}) (entity.UserPageQuery, error) {
+	q := entity.UserPageQuery{}
+
+	first, hasFirst := args["first"].(int)
+	last, hasLast := args["last"].(int)
+	switch {
+	case hasFirst && hasLast:
+		return q, apperrors.NewBadUserInput("last", "cannot be combined with first")
+	case hasFirst:
+		if first < 0 || first > maxPageSize {
+			return q, apperrors.NewBadUserInput("first", "must be between 0 and "+strconv.Itoa(maxPageSize))
+		}
+		q.First = first
+	case hasLast:
+		if last < 0 || last > maxPageSize {
+			return q, apperrors.NewBadUserInput("last", "must be between 0 and "+strconv.Itoa(maxPageSize))
+		}
+		q.Last = last
+	default:
+		q.First = defaultPageSize
+	}
+
+	for _, name := range []string{"after", "before"} {
+		s, ok := args[name].(string)
+		if !ok {
+			continue
+		}
+		c, err := decodeUserCursor(s)
+		if err != nil {
+			return q, apperrors.NewBadUserInput(name, "is not a valid cursor")
+		}
+		if name == "after" {
+			q.After = &c
+		} else {
+			q.Before = &c
+		}
+	}
+	return q, nil
+}
+
+// encodeUserCursor renders a keyset position as an opaque string for clients.
+func encodeUserCursor(c entity.UserCursor) string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(s string) (entity.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return entity.UserCursor{}, err
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return entity.UserCursor{}, strconv.ErrSyntax
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return entity.UserCursor{}, err
+	}
+	return entity.UserCursor{CreatedAt: time.Unix(0, n).UTC(), ID: id}, nil
+}
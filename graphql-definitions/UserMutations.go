@@ -0,0 +1,142 @@
+package graphql_definitions
+
+import (
+	"strings"
+
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/graphql-go/graphql"
+)
+
+var CreateUserInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"avatarURL": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+	},
+})
+
+var UpdateUserInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"avatarURL": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+	},
+})
+
+var CreateUserMutation = auth.Protect(auth.RequireRole(auth.RoleAdmin), &graphql.Field{
+	Type:        UserType,
+	Description: "Create a new user",
+	Args: graphql.FieldConfigArgument{
+		"input": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(CreateUserInputType),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		input := p.Args["input"].(map[string]interface{})
+		user := entity.User{}
+		applyUserInput(&user, input)
+		if err := user.Validate(); err != nil {
+			return nil, err
+		}
+
+		user, err = repo.Create(p.Context, user)
+		if err != nil {
+			return nil, err
+		}
+		publish(p, TopicUserCreated, user)
+		return user, nil
+	},
+})
+
+var UpdateUserMutation = auth.Protect(auth.RequireRole(auth.RoleAdmin), &graphql.Field{
+	Type:        UserType,
+	Description: "Update the name or avatar of an existing user",
+	Args: graphql.FieldConfigArgument{
+		"input": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(UpdateUserInputType),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		input := p.Args["input"].(map[string]interface{})
+		id, err := decodeUserID("id", input["id"].(string))
+		if err != nil {
+			return nil, err
+		}
+		user, err := repo.Get(p.Context, id)
+		if err != nil {
+			return nil, err
+		}
+		applyUserInput(&user, input)
+		if err := user.Validate(); err != nil {
+			return nil, err
+		}
+
+		user, err = repo.Update(p.Context, user)
+		if err != nil {
+			return nil, err
+		}
+		publish(p, TopicUserUpdated, user)
+		return user, nil
+	},
+})
+
+var DeleteUserMutation = auth.Protect(auth.RequireRole(auth.RoleAdmin), &graphql.Field{
+	Type:        UserType,
+	Description: "Delete a user, returning the removed record",
+	Args: graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := decodeUserID("id", p.Args["id"].(string))
+		if err != nil {
+			return nil, err
+		}
+		user, err := repo.Get(p.Context, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.Delete(p.Context, id); err != nil {
+			return nil, err
+		}
+		return user, nil
+	},
+})
+
+// applyUserInput copies the fields present in a create or update input onto u.
+func applyUserInput(u *entity.User, input map[string]interface{}) {
+	if name, ok := input["name"].(string); ok {
+		u.Name = strings.TrimSpace(name)
+	}
+	if avatarURL, ok := input["avatarURL"].(string); ok {
+		u.AvatarURL = strings.TrimSpace(avatarURL)
+	}
+}
@@ -0,0 +1,88 @@
+package graphql_definitions
+
+import (
+	"errors"
+
+	"github.com/chalkedgoose/act-up-api/entity"
+	"github.com/chalkedgoose/act-up-api/pubsub"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	TopicUserCreated = "userCreated"
+	TopicUserUpdated = "userUpdated"
+)
+
+var errNoPubSub = errors.New("subscriptions are not configured")
+
+// publish announces a change to subscribers when the server provides a PubSub.
+func publish(p graphql.ResolveParams, topic string, user entity.User) {
+	if ps, ok := pubsub.FromContext(p.Context); ok {
+		ps.Publish(topic, user)
+	}
+}
+
+// subscribeTo returns a Subscribe function streaming the events of topic that pass keep.
+func subscribeTo(topic string, keep func(p graphql.ResolveParams, user entity.User) bool) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ps, ok := pubsub.FromContext(p.Context)
+		if !ok {
+			return nil, errNoPubSub
+		}
+		if keep == nil {
+			return ps.Subscribe(p.Context, topic), nil
+		}
+
+		events := ps.Subscribe(p.Context, topic)
+		filtered := make(chan interface{})
+		go func() {
+			defer close(filtered)
+			for event := range events {
+				if !keep(p, event.(entity.User)) {
+					continue
+				}
+				select {
+				case filtered <- event:
+				case <-p.Context.Done():
+				}
+			}
+		}()
+		return filtered, nil
+	}
+}
+
+// resolveEvent returns the published user each subscription event is executed against.
+func resolveEvent(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+var UserCreatedSubscription = &graphql.Field{
+	Type:        UserType,
+	Description: "Emitted after a user is created",
+	Subscribe:   subscribeTo(TopicUserCreated, nil),
+	Resolve:     resolveEvent,
+}
+
+var UserUpdatedSubscription = &graphql.Field{
+	Type:        UserType,
+	Description: "Emitted after a user is updated, optionally only for the given user",
+	Args: graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.ID,
+		},
+	},
+	Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+		globalID, ok := p.Args["id"].(string)
+		if !ok {
+			return subscribeTo(TopicUserUpdated, nil)(p)
+		}
+		id, err := decodeUserID("id", globalID)
+		if err != nil {
+			return nil, err
+		}
+		return subscribeTo(TopicUserUpdated, func(p graphql.ResolveParams, user entity.User) bool {
+			return user.ID == id
+		})(p)
+	},
+	Resolve: resolveEvent,
+}
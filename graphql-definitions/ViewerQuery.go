@@ -0,0 +1,27 @@
+package graphql_definitions
+
+import (
+	"github.com/chalkedgoose/act-up-api/auth"
+	"github.com/graphql-go/graphql"
+)
+
+var GetViewerQuery = &graphql.Field{
+	Type:        UserType,
+	Description: "The user the request is authenticated as",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		principal := auth.PrincipalFromContext(p.Context)
+		if principal == nil {
+			return nil, auth.ErrUnauthenticated
+		}
+
+		repo, err := userRepository(p)
+		if err != nil {
+			return nil, err
+		}
+		user, err := repo.Get(p.Context, principal.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	},
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// ApolloTracingHeader asks for the timings of the request's operations in
+// `extensions.tracing` when Config.ApolloTracingOnRequest is set.
+const ApolloTracingHeader = "X-Apollo-Tracing"
+
+// apolloTracingKey marks a request whose operations report their timings.
+type apolloTracingKey struct{}
+
+// apolloTraceKey carries the apolloTrace of an operation.
+type apolloTraceKey struct{}
+
+// wantsApolloTracing reports whether the operations of r report their timings.
+func (h *Handler) wantsApolloTracing(r *http.Request) bool {
+	if h.apolloTracing {
+		return true
+	}
+	return h.apolloTracingOnRequest && r.Header.Get(ApolloTracingHeader) != ""
+}
+
+// apolloTrace holds the timings of an operation in the Apollo Tracing format,
+// with the duration of the execution added. Offsets and durations are in
+// nanoseconds since StartTime.
+type apolloTrace struct {
+	mu sync.Mutex
+
+	Version    int                  `json:"version"`
+	StartTime  time.Time            `json:"startTime"`
+	EndTime    time.Time            `json:"endTime"`
+	Duration   time.Duration        `json:"duration"`
+	Parsing    apolloTiming         `json:"parsing"`
+	Validation apolloTiming         `json:"validation"`
+	Execution  apolloExecutionTrace `json:"execution"`
+}
+
+type apolloTiming struct {
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+type apolloExecutionTrace struct {
+	apolloTiming
+	Resolvers []apolloResolverTrace `json:"resolvers"`
+}
+
+type apolloResolverTrace struct {
+	Path       []interface{} `json:"path"`
+	ParentType string        `json:"parentType"`
+	FieldName  string        `json:"fieldName"`
+	ReturnType string        `json:"returnType"`
+	apolloTiming
+}
+
+func newApolloTrace() *apolloTrace {
+	return &apolloTrace{
+		Version:   1,
+		StartTime: time.Now(),
+		Execution: apolloExecutionTrace{Resolvers: []apolloResolverTrace{}},
+	}
+}
+
+// time starts timing a phase of the operation, which ends when the returned
+// function is called with where to store its timing.
+func (t *apolloTrace) time() func(*apolloTiming) {
+	start := time.Since(t.StartTime)
+	return func(timing *apolloTiming) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		timing.StartOffset = start
+		timing.Duration = time.Since(t.StartTime) - start
+	}
+}
+
+// finish ends the trace and adds it to the extensions of result.
+func (t *apolloTrace) finish(result *graphql.Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.EndTime = time.Now()
+	t.Duration = t.EndTime.Sub(t.StartTime)
+	if result.Extensions == nil {
+		result.Extensions = map[string]interface{}{}
+	}
+	result.Extensions["tracing"] = t
+}
+
+// apolloTracingExtension records the phases and resolvers of the operations
+// whose context carries an apolloTrace.
+type apolloTracingExtension struct{}
+
+var _ graphql.Extension = apolloTracingExtension{}
+
+func apolloTraceFromContext(ctx context.Context) *apolloTrace {
+	t, _ := ctx.Value(apolloTraceKey{}).(*apolloTrace)
+	return t
+}
+
+func (apolloTracingExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (apolloTracingExtension) Name() string {
+	return "tracing"
+}
+
+func (apolloTracingExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	t := apolloTraceFromContext(ctx)
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	done := t.time()
+	return ctx, func(error) { done(&t.Parsing) }
+}
+
+func (apolloTracingExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	t := apolloTraceFromContext(ctx)
+	if t == nil {
+		return ctx, func([]gqlerrors.FormattedError) {}
+	}
+	done := t.time()
+	return ctx, func([]gqlerrors.FormattedError) { done(&t.Validation) }
+}
+
+func (apolloTracingExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	t := apolloTraceFromContext(ctx)
+	if t == nil {
+		return ctx, func(*graphql.Result) {}
+	}
+	done := t.time()
+	return ctx, func(*graphql.Result) { done(&t.Execution.apolloTiming) }
+}
+
+func (apolloTracingExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	t := apolloTraceFromContext(ctx)
+	if t == nil {
+		return ctx, func(interface{}, error) {}
+	}
+	resolver := apolloResolverTrace{
+		Path:       info.Path.AsArray(),
+		ParentType: info.ParentType.Name(),
+		FieldName:  info.FieldName,
+		ReturnType: info.ReturnType.String(),
+	}
+	done := t.time()
+	return ctx, func(interface{}, error) {
+		done(&resolver.apolloTiming)
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.Execution.Resolvers = append(t.Execution.Resolvers, resolver)
+	}
+}
+
+// HasResult is false as graphql-go would then add a result to every
+// operation; the handler adds the trace of the operations asking for one.
+func (apolloTracingExtension) HasResult() bool {
+	return false
+}
+
+func (apolloTracingExtension) GetResult(context.Context) interface{} {
+	return nil
+}
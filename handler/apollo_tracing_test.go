@@ -0,0 +1,151 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apolloTiming struct {
+	StartOffset int64 `json:"startOffset"`
+	Duration    int64 `json:"duration"`
+}
+
+type apolloTrace struct {
+	Version    int          `json:"version"`
+	StartTime  time.Time    `json:"startTime"`
+	EndTime    time.Time    `json:"endTime"`
+	Duration   int64        `json:"duration"`
+	Parsing    apolloTiming `json:"parsing"`
+	Validation apolloTiming `json:"validation"`
+	Execution  struct {
+		apolloTiming
+		Resolvers []struct {
+			Path       []interface{} `json:"path"`
+			ParentType string        `json:"parentType"`
+			FieldName  string        `json:"fieldName"`
+			ReturnType string        `json:"returnType"`
+			apolloTiming
+		} `json:"resolvers"`
+	} `json:"execution"`
+}
+
+type tracedResult struct {
+	Data       map[string]interface{} `json:"data"`
+	Extensions struct {
+		Tracing *apolloTrace `json:"tracing"`
+	} `json:"extensions"`
+}
+
+// sleepySchema has a `nap` field taking 20ms to resolve a list of names.
+func sleepySchema(t *testing.T) *graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"nap": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						time.Sleep(20 * time.Millisecond)
+						return []string{"a", "b"}, nil
+					},
+				},
+				"hello": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return "world", nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func tracedRequest(t *testing.T, h *handler.Handler, body string, header bool) []byte {
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if header {
+		req.Header.Set(handler.ApolloTracingHeader, "1")
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	return rr.Body.Bytes()
+}
+
+func TestHandler_ApolloTracingOnRequest(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: sleepySchema(t), ApolloTracingOnRequest: true})
+
+	var result tracedResult
+	json.Unmarshal(tracedRequest(t, h, `{"query": "{ nap hello }"}`, false), &result)
+	if result.Extensions.Tracing != nil {
+		t.Fatal("timings were added without the header")
+	}
+
+	json.Unmarshal(tracedRequest(t, h, `{"query": "{ nap hello }"}`, true), &result)
+	trace := result.Extensions.Tracing
+	if trace == nil {
+		t.Fatal("no timings despite the header")
+	}
+	if trace.Version != 1 || !trace.EndTime.After(trace.StartTime) ||
+		trace.Duration < trace.Execution.StartOffset+trace.Execution.Duration {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	if trace.Parsing.Duration <= 0 || trace.Validation.StartOffset < trace.Parsing.StartOffset+trace.Parsing.Duration ||
+		trace.Execution.StartOffset < trace.Validation.StartOffset+trace.Validation.Duration {
+		t.Fatalf("phases out of order: %+v %+v %+v", trace.Parsing, trace.Validation, trace.Execution.apolloTiming)
+	}
+	if trace.Execution.Duration < int64(20*time.Millisecond) {
+		t.Fatalf("execution took %v", time.Duration(trace.Execution.Duration))
+	}
+
+	if len(trace.Execution.Resolvers) != 2 {
+		t.Fatalf("unexpected resolvers %+v", trace.Execution.Resolvers)
+	}
+	for _, resolver := range trace.Execution.Resolvers {
+		if resolver.ParentType != "Query" || len(resolver.Path) != 1 || resolver.Path[0] != resolver.FieldName ||
+			resolver.StartOffset < trace.Execution.StartOffset {
+			t.Fatalf("unexpected resolver %+v", resolver)
+		}
+		if resolver.FieldName == "nap" &&
+			(resolver.ReturnType != "[String]" || resolver.Duration < int64(20*time.Millisecond)) {
+			t.Fatalf("unexpected resolver %+v", resolver)
+		}
+	}
+}
+
+func TestHandler_ApolloTracing(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: sleepySchema(t), ApolloTracing: true, MaxBatchSize: 2})
+
+	var results []tracedResult
+	json.Unmarshal(tracedRequest(t, h, `[{"query": "{ nap }"}, {"query": "{ hello }"}]`, false), &results)
+	if len(results) != 2 || results[0].Extensions.Tracing == nil || results[1].Extensions.Tracing == nil {
+		t.Fatalf("every operation should carry its timings: %+v", results)
+	}
+	for i, field := range []string{"nap", "hello"} {
+		resolvers := results[i].Extensions.Tracing.Execution.Resolvers
+		if len(resolvers) != 1 || resolvers[0].FieldName != field {
+			t.Fatalf("operation %d has the resolvers %+v", i, resolvers)
+		}
+	}
+}
+
+func TestHandler_ApolloTracingDisabled(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: sleepySchema(t)})
+
+	body := tracedRequest(t, h, `{"query": "{ hello }"}`, true)
+	if strings.Contains(string(body), "extensions") {
+		t.Fatalf("timings were added without being enabled: %s", body)
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// NewBatchRequestOptions parses a http.Request that may carry a JSON array of
+// operations; the batch is nil when the array is malformed. For any other
+// request it returns the single RequestOptions of NewRequestOptions and false.
+func NewBatchRequestOptions(r *http.Request) ([]*RequestOptions, bool) {
+	if r.Method != http.MethodPost || r.Body == nil || getFromForm(r.URL.Query()) != nil {
+		return []*RequestOptions{NewRequestOptions(r)}, false
+	}
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	if contentType == ContentTypeGraphQL || contentType == ContentTypeFormURLEncoded {
+		return []*RequestOptions{NewRequestOptions(r)}, false
+	}
+	if contentType == ContentTypeMultipartFormData {
+		batch, isBatch, err := newMultipartRequestOptions(r)
+		if err != nil {
+			return []*RequestOptions{{}}, false
+		}
+		return batch, isBatch
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return []*RequestOptions{{}}, false
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return []*RequestOptions{decodeRequestOptions(body)}, false
+	}
+
+	var operations []json.RawMessage
+	if err := json.Unmarshal(trimmed, &operations); err != nil {
+		return nil, true
+	}
+	batch := make([]*RequestOptions, len(operations))
+	for i, operation := range operations {
+		batch[i] = decodeRequestOptions(operation)
+	}
+	return batch, true
+}
+
+// serveBatch executes every operation of a batch and writes their results as
+// a JSON array in request order. Up to batchConcurrency operations run at once.
+func (h *Handler) serveBatch(ctx context.Context, w http.ResponseWriter, r *http.Request, mediaType string, batch []*RequestOptions) {
+
+	var batchErr string
+	switch {
+	case h.maxBatchSize <= 0:
+		batchErr = "batched operations are not enabled"
+	case batch == nil:
+		batchErr = "batch is not a valid JSON array of operations"
+	case len(batch) == 0:
+		batchErr = "batch must contain at least one operation"
+	case len(batch) > h.maxBatchSize:
+		batchErr = fmt.Sprintf("batch of %d operations exceeds the maximum of %d", len(batch), h.maxBatchSize)
+	}
+	if batchErr != "" {
+		result := requestError(batchErr, "BAD_REQUEST")
+		h.writeJSON(w, mediaType, resultStatus(mediaType, true), responseBody(mediaType, result, true))
+		return
+	}
+
+	concurrency := h.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	params := make([]graphql.Params, len(batch))
+	results := make([]*graphql.Result, len(batch))
+	requestErrs := make([]bool, len(batch))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, opts := range batch {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, opts *RequestOptions) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			var op *operation
+			op, results[i], requestErrs[i] = h.run(ctx, r, opts)
+			params[i] = op.params
+		}(i, opts)
+	}
+	wg.Wait()
+
+	bodies := make([]interface{}, len(results))
+	for i, result := range results {
+		bodies[i] = responseBody(mediaType, result, requestErrs[i])
+	}
+	buff := h.writeJSON(w, mediaType, http.StatusOK, bodies)
+
+	if h.resultCallbackFn != nil {
+		for i := range batch {
+			h.resultCallbackFn(ctx, &params[i], results[i], buff)
+		}
+	}
+}
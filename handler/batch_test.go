@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func executeBatch(t *testing.T, h *handler.Handler, body string) []*graphql.Result {
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	var results []*graphql.Result
+	if err := json.Unmarshal(resp.Body.Bytes(), &results); err != nil {
+		t.Fatalf("expected an array of results: %v\n%s", err, resp.Body.String())
+	}
+	return results
+}
+
+func TestHandler_Batch(t *testing.T) {
+	for _, concurrency := range []int{0, 3} {
+		h := handler.New(&handler.Config{
+			Schema:           &testutil.StarWarsSchema,
+			MaxBatchSize:     5,
+			BatchConcurrency: concurrency,
+		})
+
+		results := executeBatch(t, h, `[
+			{"query": "query { hero { name } }"},
+			{"query": "query($id: String!) { human(id: $id) { name } }", "variables": {"id": "1000"}},
+			{"query": "query { nope }"}
+		]`)
+
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(results))
+		}
+		if !reflect.DeepEqual(results[0], heroNameResult) {
+			t.Fatalf("wrong first result, graphql result diff: %v", testutil.Diff(heroNameResult, results[0]))
+		}
+		expected := &graphql.Result{
+			Data: map[string]interface{}{
+				"human": map[string]interface{}{"name": "Luke Skywalker"},
+			},
+		}
+		if !reflect.DeepEqual(results[1], expected) {
+			t.Fatalf("wrong second result, graphql result diff: %v", testutil.Diff(expected, results[1]))
+		}
+		if !results[2].HasErrors() {
+			t.Fatalf("expected the invalid third operation to fail on its own")
+		}
+	}
+}
+
+func TestHandler_Batch_Rejected(t *testing.T) {
+	cases := map[string]struct {
+		maxBatchSize int
+		body         string
+		expected     string
+	}{
+		"disabled": {
+			body:     `[{"query": "query { hero { name } }"}]`,
+			expected: "batched operations are not enabled",
+		},
+		"too large": {
+			maxBatchSize: 1,
+			body:         `[{"query": "query { hero { name } }"}, {"query": "query { hero { name } }"}]`,
+			expected:     "batch of 2 operations exceeds the maximum of 1",
+		},
+		"empty": {
+			maxBatchSize: 1,
+			body:         `[]`,
+			expected:     "batch must contain at least one operation",
+		},
+		"malformed": {
+			maxBatchSize: 1,
+			body:         `[{"query": }]`,
+			expected:     "batch is not a valid JSON array of operations",
+		},
+	}
+	for name, tc := range cases {
+		h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, MaxBatchSize: tc.maxBatchSize})
+		req, _ := http.NewRequest("POST", "/graphql", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		result, _ := executeTest(t, h, req)
+		if len(result.Errors) != 1 || result.Errors[0].Message != tc.expected {
+			t.Fatalf("%s: expected error %q, got %v", name, tc.expected, result.Errors)
+		}
+	}
+}
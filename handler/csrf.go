@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+)
+
+// CSRFPreflightHeaders are the request headers that prove a POST was sent by
+// a client able to trigger a CORS preflight; any non-empty value is accepted.
+var CSRFPreflightHeaders = []string{"Apollo-Require-Preflight", "X-Apollo-Operation-Name"}
+
+// simpleContentTypes are the Content-Types a browser sends cross-origin without a preflight.
+var simpleContentTypes = map[string]bool{
+	ContentTypeFormURLEncoded: true,
+	"multipart/form-data":     true,
+	"text/plain":              true,
+}
+
+// csrfSafe reports whether r could not have been sent by a cross-site form or
+// simple fetch: it is not a POST, carries a Content-Type a browser would
+// preflight, or sets one of CSRFPreflightHeaders.
+func csrfSafe(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return true
+	}
+	for _, header := range CSRFPreflightHeaders {
+		if r.Header.Get(header) != "" {
+			return true
+		}
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && !simpleContentTypes[mediaType]
+}
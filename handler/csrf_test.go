@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_CSRFPrevention(t *testing.T) {
+	cases := map[string]struct {
+		contentType        string
+		headers            map[string]string
+		body               string
+		expectedStatusCode int
+	}{
+		"allows JSON": {
+			contentType:        "application/json",
+			body:               `{"query": "{ hero { name } }"}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		"allows application/graphql": {
+			contentType:        "application/graphql",
+			body:               `{ hero { name } }`,
+			expectedStatusCode: http.StatusOK,
+		},
+		"blocks form posts": {
+			contentType:        "application/x-www-form-urlencoded",
+			body:               `query={ hero { name } }`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"blocks text/plain": {
+			contentType:        "text/plain",
+			body:               `{"query": "{ hero { name } }"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"blocks a missing Content-Type": {
+			body:               `{"query": "{ hero { name } }"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"allows form posts with a preflight header": {
+			contentType:        "application/x-www-form-urlencoded",
+			headers:            map[string]string{"Apollo-Require-Preflight": "true"},
+			body:               `query={ hero { name } }`,
+			expectedStatusCode: http.StatusOK,
+		},
+		"allows an operation name header": {
+			contentType:        "application/x-www-form-urlencoded",
+			headers:            map[string]string{"X-Apollo-Operation-Name": "Hero"},
+			body:               `query={ hero { name } }`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, CSRFPrevention: true})
+	for tcID, tc := range cases {
+		t.Run(tcID, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatusCode {
+				t.Fatalf("wrong status code, expected %v, got %v: %s", tc.expectedStatusCode, rr.Code, rr.Body)
+			}
+			if tc.expectedStatusCode == http.StatusBadRequest && !strings.Contains(rr.Body.String(), "Cross-Site Request Forgery") {
+				t.Fatalf("expected a CSRF error, got %s", rr.Body)
+			}
+		})
+	}
+}
+
+func TestHandler_CSRFPrevention_Disabled(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`query={ hero { name } }`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.New(&handler.Config{Schema: &testutil.StarWarsSchema}).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected server response %v", rr.Code)
+	}
+}
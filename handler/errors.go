@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"golang.org/x/exp/slog"
+)
+
+// maskedMessage replaces the message of internal errors when they are masked.
+const maskedMessage = "internal server error"
+
+// isInternal reports whether err is not meant for clients: it was raised by a
+// resolver without a code, or explicitly coded as apperrors.Internal. Errors
+// without a path come from parsing, validation or the handler itself and
+// describe the request, so they are never internal.
+func isInternal(err gqlerrors.FormattedError) bool {
+	code, _ := err.Extensions["code"].(string)
+	return code == string(apperrors.Internal) || (code == "" && len(err.Path) > 0)
+}
+
+// reportInternal logs err under a new correlation ID, which is also added to
+// its extensions so a client report can be matched with the log. It is logged
+// through logger when set, the standard logger otherwise. With mask set the
+// message is replaced, as it may reveal implementation details.
+func reportInternal(ctx context.Context, logger *slog.Logger, err gqlerrors.FormattedError, mask bool) gqlerrors.FormattedError {
+	id := correlationID()
+	if logger != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "graphql internal error",
+			slog.String("correlation_id", id),
+			slog.Any("path", err.Path),
+			slog.String("error", err.Message))
+	} else {
+		log.Printf("internal error %s at %v: %s", id, err.Path, err.Message)
+	}
+
+	extensions := map[string]interface{}{}
+	for key, value := range err.Extensions {
+		extensions[key] = value
+	}
+	extensions["code"] = string(apperrors.Internal)
+	extensions["correlationId"] = id
+	err.Extensions = extensions
+	if mask {
+		err.Message = maskedMessage
+	}
+	return err
+}
+
+func correlationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingSchema has a `leak` field failing with an uncoded error and a
+// `missing` field failing with a NOT_FOUND error.
+func failingSchema(t *testing.T) *graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"leak": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, errors.New("dial tcp 10.0.0.3:5432: connection refused")
+					},
+				},
+				"missing": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, apperrors.New(apperrors.NotFound, "user not found")
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+type testError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+func queryErrors(t *testing.T, h *handler.Handler, query string) []testError {
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "`+query+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var result struct {
+		Errors []testError `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result.Errors
+}
+
+func TestHandler_InternalErrors(t *testing.T) {
+	for _, mask := range []bool{false, true} {
+		h := handler.New(&handler.Config{Schema: failingSchema(t), MaskInternalErrors: mask})
+
+		errs := queryErrors(t, h, "{ leak }")
+		if len(errs) != 1 || errs[0].Extensions["code"] != "INTERNAL" || errs[0].Extensions["correlationId"] == "" {
+			t.Fatalf("mask %v: expected an INTERNAL error with a correlation ID, got %+v", mask, errs)
+		}
+		if leaked := strings.Contains(errs[0].Message, "10.0.0.3"); leaked == mask {
+			t.Fatalf("mask %v: unexpected message %q", mask, errs[0].Message)
+		}
+
+		errs = queryErrors(t, h, "{ missing }")
+		expected := testError{Message: "user not found", Extensions: map[string]interface{}{"code": "NOT_FOUND"}}
+		if len(errs) != 1 || errs[0].Message != expected.Message || len(errs[0].Extensions) != 1 || errs[0].Extensions["code"] != "NOT_FOUND" {
+			t.Fatalf("mask %v: expected %+v to be left alone, got %+v", mask, expected, errs)
+		}
+
+		// request errors describe the query and have no path
+		errs = queryErrors(t, h, "{ unknown }")
+		if len(errs) != 1 || errs[0].Extensions != nil || !strings.Contains(errs[0].Message, "unknown") {
+			t.Fatalf("mask %v: expected the validation error to be left alone, got %+v", mask, errs)
+		}
+	}
+}
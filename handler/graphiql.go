@@ -16,7 +16,7 @@ type graphiqlData struct {
 }
 
 // renderGraphiQL renders the GraphiQL GUI
-func renderGraphiQL(w http.ResponseWriter, params graphql.Params) {
+func renderGraphiQL(w http.ResponseWriter, params graphql.Params, result *graphql.Result) {
 	t := template.New("GraphiQL")
 	t, err := t.Parse(graphiqlTemplate)
 	if err != nil {
@@ -40,12 +40,12 @@ func renderGraphiQL(w http.ResponseWriter, params graphql.Params) {
 	if params.RequestString == "" {
 		resString = ""
 	} else {
-		result, err := json.MarshalIndent(graphql.Do(params), "", "  ")
+		res, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		resString = string(result)
+		resString = string(res)
 	}
 
 	d := graphiqlData{
@@ -157,14 +157,16 @@ const graphiqlTemplate = `
 
 	var fetchURL = locationQuery(otherParams);
 
-	// Defines a GraphQL fetcher using the fetch API.
-	function graphQLFetcher(graphQLParams) {
+	// Defines a GraphQL fetcher using the fetch API, sending along the headers
+	// of the header editor.
+	function graphQLFetcher(graphQLParams, opts) {
+	    var headers = Object.assign({
+	        'Accept': 'application/json',
+	        'Content-Type': 'application/json'
+	    }, opts && opts.headers);
 	    return fetch(fetchURL, {
 	        method: 'post',
-	        headers: {
-	            'Accept': 'application/json',
-	            'Content-Type': 'application/json'
-	        },
+	        headers: headers,
 	        body: JSON.stringify(graphQLParams),
 	        credentials: 'include',
 	    }).then(function(response) {
@@ -202,6 +204,7 @@ const graphiqlTemplate = `
 	ReactDOM.render(
 	    React.createElement(GraphiQL, {
 	        fetcher: graphQLFetcher,
+	        headerEditorEnabled: true,
 	        onEditQuery: onEditQuery,
 	        onEditVariables: onEditVariables,
 	        onEditOperationName: onEditOperationName,
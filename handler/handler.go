@@ -3,12 +3,20 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/gorilla/websocket"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 /**
@@ -24,18 +32,40 @@ const (
 type ResultCallbackFn func(ctx context.Context, params *graphql.Params, result *graphql.Result, responseBody []byte)
 
 type Handler struct {
-	Schema           *graphql.Schema
-	pretty           bool
-	graphiql         bool
-	rootObjectFn     RootObjectFn
-	resultCallbackFn ResultCallbackFn
-	formatErrorFn    func(err error) gqlerrors.FormattedError
+	Schema                 *graphql.Schema
+	pretty                 bool
+	graphiql               bool
+	rootObjectFn           RootObjectFn
+	resultCallbackFn       ResultCallbackFn
+	formatErrorFn          func(err error) gqlerrors.FormattedError
+	limits                 queryLimits
+	persistedQueries       PersistedQueryCache
+	trustedDocuments       *TrustedDocuments
+	maxBatchSize           int
+	batchConcurrency       int
+	wsInitFn               WebSocketInitFn
+	wsInitTimeout          time.Duration
+	wsReadLimit            int64
+	csrfPrevention         bool
+	maxUploadSize          int64
+	timeout                time.Duration
+	maskInternalErrors     bool
+	tracer                 trace.Tracer
+	metrics                *Metrics
+	apolloTracing          bool
+	apolloTracingOnRequest bool
+	logger                 *operationLogger
+	fieldUsage             *usage.Recorder
 }
 
 type RequestOptions struct {
 	Query         string                 `json:"query" url:"query" graphql-definitions:"query"`
 	Variables     map[string]interface{} `json:"variables" url:"variables" graphql-definitions:"variables"`
 	OperationName string                 `json:"operationName" url:"operationName" graphql-definitions:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty" url:"extensions" graphql-definitions:"extensions"`
+	// DocumentID names a trusted document to execute in place of Query. Clients
+	// may also send it as `id`.
+	DocumentID string `json:"documentId,omitempty" url:"documentId" graphql-definitions:"documentId"`
 }
 
 // a workaround for getting`variables` as a JSON string
@@ -47,16 +77,27 @@ type requestOptionsCompatibility struct {
 
 func getFromForm(values url.Values) *RequestOptions {
 	query := values.Get("query")
-	if query != "" {
+	extensionsStr := values.Get("extensions")
+	documentID := values.Get("documentId")
+	if documentID == "" {
+		documentID = values.Get("id")
+	}
+	if query != "" || extensionsStr != "" || documentID != "" {
 		// get variables map
 		variables := make(map[string]interface{}, len(values))
 		variablesStr := values.Get("variables")
 		json.Unmarshal([]byte(variablesStr), &variables)
 
+		// persisted queries send only a hash in `extensions`
+		var extensions map[string]interface{}
+		json.Unmarshal([]byte(extensionsStr), &extensions)
+
 		return &RequestOptions{
 			Query:         query,
 			Variables:     variables,
 			OperationName: values.Get("operationName"),
+			Extensions:    extensions,
+			DocumentID:    documentID,
 		}
 	}
 
@@ -102,33 +143,167 @@ func NewRequestOptions(r *http.Request) *RequestOptions {
 
 		return &RequestOptions{}
 
+	case ContentTypeMultipartFormData:
+		batch, isBatch, err := newMultipartRequestOptions(r)
+		if err != nil || isBatch {
+			return &RequestOptions{}
+		}
+		return batch[0]
+
 	case ContentTypeJSON:
 		fallthrough
 	default:
-		var opts RequestOptions
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			return &opts
+			return &RequestOptions{}
 		}
-		err = json.Unmarshal(body, &opts)
-		if err != nil {
-			// Probably `variables` was sent as a string instead of an object.
-			// So, we try to be polite and try to parse that as a JSON string
-			var optsCompatible requestOptionsCompatibility
-			json.Unmarshal(body, &optsCompatible)
-			json.Unmarshal([]byte(optsCompatible.Variables), &opts.Variables)
+		return decodeRequestOptions(body)
+	}
+}
+
+// decodeRequestOptions parses one JSON encoded operation.
+func decodeRequestOptions(body []byte) *RequestOptions {
+	var opts RequestOptions
+	err := json.Unmarshal(body, &opts)
+	if err != nil {
+		// Probably `variables` was sent as a string instead of an object.
+		// So, we try to be polite and try to parse that as a JSON string
+		var optsCompatible requestOptionsCompatibility
+		json.Unmarshal(body, &optsCompatible)
+		json.Unmarshal([]byte(optsCompatible.Variables), &opts.Variables)
+	}
+	if opts.DocumentID == "" {
+		var documentID struct {
+			ID string `json:"id"`
 		}
-		return &opts
+		json.Unmarshal(body, &documentID)
+		opts.DocumentID = documentID.ID
 	}
+	return &opts
 }
 
 // ContextHandler provides an entrypoint into executing graphQL queries with a
 // user-provided context.
 func (h *Handler) ContextHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if h.tracer != nil {
+		ctx = extractTraceContext(ctx, r)
+	}
+	if h.metrics != nil {
+		defer h.metrics.track(r)()
+	}
+	if h.wantsApolloTracing(r) {
+		ctx = context.WithValue(ctx, apolloTracingKey{}, true)
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(ctx, w, r)
+		return
+	}
+	if h.csrfPrevention && !csrfSafe(r) {
+		h.writeRequestError(w, ContentTypeJSON, http.StatusBadRequest,
+			"this request has been blocked as a potential Cross-Site Request Forgery, "+
+				"send it with a Content-Type of application/json or an Apollo-Require-Preflight header")
+		return
+	}
+	if !h.supportedContentType(r) {
+		h.writeRequestError(w, ContentTypeJSON, http.StatusUnsupportedMediaType,
+			"unsupported Content-Type "+r.Header.Get("Content-Type"))
+		return
+	}
+	if isMultipart(r) {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+		_, _, err := newMultipartRequestOptions(r)
+		if r.MultipartForm != nil {
+			defer r.MultipartForm.RemoveAll()
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				h.writeRequestError(w, ContentTypeJSON, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("request body exceeds the maximum upload size of %d bytes", h.maxUploadSize))
+				return
+			}
+			h.writeRequestError(w, ContentTypeJSON, http.StatusBadRequest, "invalid multipart request: "+err.Error())
+			return
+		}
+	}
+	if acceptsEventStream(r) {
+		h.serveSSE(ctx, w, r)
+		return
+	}
+
+	acceptHeader := r.Header.Get("Accept")
+	mediaType := negotiateMediaType(acceptHeader)
+
+	_, raw := r.URL.Query()["raw"]
+	renderGraphiQLPage := h.graphiql && !raw &&
+		!strings.Contains(acceptHeader, "application/json") && strings.Contains(acceptHeader, "text/html")
+	if mediaType == "" && strings.Contains(acceptHeader, "text/html") {
+		// browsers navigating to the endpoint get GraphiQL or plain JSON
+		mediaType = ContentTypeJSON
+	}
+	if mediaType == "" {
+		h.writeRequestError(w, ContentTypeJSON, http.StatusNotAcceptable,
+			"none of the accepted media types can be produced, accept "+ContentTypeGraphQLResponse)
+		return
+	}
+
 	// get query
-	opts := NewRequestOptions(r)
+	batch, isBatch := NewBatchRequestOptions(r)
+	if isBatch {
+		h.serveBatch(ctx, w, r, mediaType, batch)
+		return
+	}
 
 	// execute graphql query
+	start := time.Now()
+	op, preflightErr := h.prepare(ctx, r, batch[0])
+	if message := refuseGET(r, op, ast.OperationTypeQuery); message != "" {
+		w.Header().Set("Allow", http.MethodPost)
+		if renderGraphiQLPage {
+			renderGraphiQL(w, op.params, requestError(message, "BAD_REQUEST"))
+			return
+		}
+		h.writeRequestError(w, mediaType, http.StatusMethodNotAllowed, message)
+		return
+	}
+	result, requestErr := h.execute(op, preflightErr)
+	h.formatErrors(ctx, result)
+	h.observe(r, op, result, start)
+
+	if renderGraphiQLPage {
+		renderGraphiQL(w, op.params, result)
+		return
+	}
+
+	buff := h.writeJSON(w, mediaType, resultStatus(mediaType, requestErr), responseBody(mediaType, result, requestErr))
+
+	if h.resultCallbackFn != nil {
+		h.resultCallbackFn(ctx, &op.params, result, buff)
+	}
+}
+
+// run executes a single operation of the request, applying persisted
+// documents, query limits and FormatErrorFn, and records it in the metrics and
+// logs. It reports whether the result is a request error, as execute does.
+func (h *Handler) run(ctx context.Context, r *http.Request, opts *RequestOptions) (*operation, *graphql.Result, bool) {
+	start := time.Now()
+	op, preflightErr := h.prepare(ctx, r, opts)
+	result, requestErr := h.execute(op, preflightErr)
+	h.formatErrors(ctx, result)
+	h.observe(r, op, result, start)
+	return op, result, requestErr
+}
+
+// prepare resolves the document of opts, parses it and builds the params to
+// execute it with, reporting an error when no executable document could be
+// determined.
+func (h *Handler) prepare(ctx context.Context, r *http.Request, opts *RequestOptions) (*operation, *gqlerrors.FormattedError) {
+	fromCache := opts.Query == "" && opts.Extensions["persistedQuery"] != nil
+	preflightErr := h.loadPersistedQuery(ctx, opts)
+	if preflightErr == nil {
+		preflightErr = h.loadTrustedDocument(opts)
+	}
+
 	params := graphql.Params{
 		Schema:         *h.Schema,
 		RequestString:  opts.Query,
@@ -136,47 +311,124 @@ func (h *Handler) ContextHandler(ctx context.Context, w http.ResponseWriter, r *
 		OperationName:  opts.OperationName,
 		Context:        ctx,
 	}
+	if h.metrics != nil {
+		params.Context = context.WithValue(ctx, metricsKey{}, h.metrics)
+	}
 	if h.rootObjectFn != nil {
 		params.RootObject = h.rootObjectFn(ctx, r)
 	}
-	result := graphql.Do(params)
+	op := newOperation(params)
+	// in trusted mode every document that passed is from the manifest
+	op.persisted = preflightErr == nil && (fromCache || h.trustedDocuments != nil)
+	return op, preflightErr
+}
 
+// formatErrors applies FormatErrorFn to the errors of result, an operation
+// executed with ctx. Without one, internal errors are reported and masked if
+// configured to.
+func (h *Handler) formatErrors(ctx context.Context, result *graphql.Result) {
 	if formatErrorFn := h.formatErrorFn; formatErrorFn != nil && len(result.Errors) > 0 {
 		formatted := make([]gqlerrors.FormattedError, len(result.Errors))
 		for i, formattedError := range result.Errors {
 			formatted[i] = formatErrorFn(formattedError.OriginalError())
 		}
 		result.Errors = formatted
+		return
+	}
+	var logger *slog.Logger
+	if h.logger != nil {
+		logger = h.logger.logger
 	}
+	for i, formattedError := range result.Errors {
+		if isInternal(formattedError) {
+			result.Errors[i] = reportInternal(ctx, logger, formattedError, h.maskInternalErrors)
+		}
+	}
+}
 
-	if h.graphiql {
-		acceptHeader := r.Header.Get("Accept")
-		_, raw := r.URL.Query()["raw"]
-		if !raw && !strings.Contains(acceptHeader, "application/json") && strings.Contains(acceptHeader, "text/html") {
-			renderGraphiQL(w, params)
-			return
+// observe records an operation of r started at start in the metrics, the logs
+// and the field usage, if enabled.
+func (h *Handler) observe(r *http.Request, op *operation, result *graphql.Result, start time.Time) {
+	if h.metrics == nil && h.logger == nil && h.fieldUsage == nil {
+		return
+	}
+	duration := time.Since(start)
+	opType, opName := op.describe()
+	if h.metrics != nil {
+		// clients choose the names of the documents they send, so only those of
+		// persisted documents label metrics; the others share a single series
+		labelName := opName
+		if !op.persisted {
+			labelName = otherOperationName
 		}
+		h.metrics.observe(opType, labelName, result, duration)
+	}
+	if h.logger != nil {
+		h.logger.log(r, op, opType, opName, result, duration)
 	}
+	if h.fieldUsage != nil && result.Data != nil {
+		h.recordFieldUsage(r, op, opName)
+	}
+}
 
+// writeJSON writes v as the response body in mediaType and returns the bytes written.
+func (h *Handler) writeJSON(w http.ResponseWriter, mediaType string, status int, v interface{}) []byte {
 	// use proper JSON Header
-	w.Header().Add("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
 
 	var buff []byte
 	if h.pretty {
-		w.WriteHeader(http.StatusOK)
-		buff, _ = json.MarshalIndent(result, "", "\t")
+		w.WriteHeader(status)
+		buff, _ = json.MarshalIndent(v, "", "\t")
 
 		w.Write(buff)
 	} else {
-		w.WriteHeader(http.StatusOK)
-		buff, _ = json.Marshal(result)
+		w.WriteHeader(status)
+		buff, _ = json.Marshal(v)
 
 		w.Write(buff)
 	}
+	return buff
+}
 
-	if h.resultCallbackFn != nil {
-		h.resultCallbackFn(ctx, &params, result, buff)
+// execute runs op unless an earlier step of the request or a query limit
+// already produced an error, which is then the only error of the result. It
+// reports whether the result is a request error: one of those, or a document
+// graphql.Do refused to execute.
+func (h *Handler) execute(op *operation, err *gqlerrors.FormattedError) (*graphql.Result, bool) {
+	if err == nil && h.limits.enabled() {
+		err = h.limits.check(op)
+	}
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{*err}}, true
+	}
+	result := h.do(op)
+	return result, failedBeforeExecution(result)
+}
+
+// do executes op in a span of its own when tracing, adding the timings of the
+// operation to its result when the request asked for them.
+func (h *Handler) do(op *operation) *graphql.Result {
+	params := op.params
+	var timings *apolloTrace
+	if params.Context.Value(apolloTracingKey{}) != nil {
+		timings = newApolloTrace()
+		params.Context = context.WithValue(params.Context, apolloTraceKey{}, timings)
+	}
+	var endSpan func(*graphql.Result)
+	if h.tracer != nil {
+		params.Context, endSpan = h.startOperation(params.Context, op)
+	}
+
+	result := h.doWithTimeout(params)
+
+	if timings != nil {
+		timings.finish(result)
 	}
+	if endSpan != nil {
+		endSpan(result)
+	}
+	return result
 }
 
 // ServeHTTP provides an entrypoint into executing graphQL queries.
@@ -188,12 +440,107 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type RootObjectFn func(ctx context.Context, r *http.Request) map[string]interface{}
 
 type Config struct {
+	// Schema is the schema operations are executed against. It is not modified:
+	// the extensions Timeout, TracerProvider, Metrics and ApolloTracing need are
+	// added to a copy owned by the handler, so handlers may share a schema.
 	Schema           *graphql.Schema
 	Pretty           bool
 	GraphiQL         bool
 	RootObjectFn     RootObjectFn
 	ResultCallbackFn ResultCallbackFn
 	FormatErrorFn    func(err error) gqlerrors.FormattedError
+
+	// MaxDepth, MaxComplexity, MaxFields and MaxAliases reject operations whose
+	// parsed document exceeds them before anything is executed. Zero disables a check.
+	MaxDepth      int
+	MaxComplexity int
+	MaxFields     int
+	MaxAliases    int
+	// FieldCosts overrides the complexity cost of 1 per field, keyed by
+	// "Type.field" (e.g. "RootQuery.users"). Selections below a field taking a
+	// `first` or `last` argument are multiplied by its value.
+	FieldCosts map[string]int
+
+	// PersistedQueryCache stores queries registered through automatic persisted
+	// queries. An in-memory LRU of DefaultPersistedQueryCacheSize is used when nil.
+	PersistedQueryCache PersistedQueryCache
+
+	// TrustedDocuments, when set, locks the handler down to the operations of the
+	// manifest: clients send a `documentId` and any other query text is rejected.
+	TrustedDocuments *TrustedDocuments
+
+	// MaxBatchSize enables JSON array bodies of up to that many operations,
+	// answered with an array of results in the same order. Zero disables batching.
+	MaxBatchSize int
+	// BatchConcurrency is how many operations of a batch execute at once; values
+	// below 2 run them one after another.
+	BatchConcurrency int
+
+	// WebSocketInitFn authorizes graphql-transport-ws connections from their
+	// connection_init payload. When nil every connection is acknowledged.
+	WebSocketInitFn WebSocketInitFn
+	// WebSocketInitTimeout is how long a connection may take to send
+	// connection_init; DefaultWebSocketInitTimeout is used when zero.
+	WebSocketInitTimeout time.Duration
+	// WebSocketReadLimit is the largest message in bytes a connection may send
+	// before it is closed with 1009; DefaultWebSocketReadLimit is used when zero.
+	WebSocketReadLimit int64
+
+	// CSRFPrevention blocks POST requests a browser would send cross-origin
+	// without a preflight: they must use a Content-Type other than form data or
+	// text/plain, or set one of CSRFPreflightHeaders.
+	CSRFPrevention bool
+
+	// MaxUploadSize enables multipart requests carrying files for the Upload
+	// scalar and caps their total size in bytes. Zero rejects multipart requests.
+	MaxUploadSize int64
+
+	// Timeout bounds the execution of each query and mutation. The context of
+	// every resolver is cancelled when it elapses; fields failing with
+	// context.DeadlineExceeded then get a TIMEOUT error and whatever data was
+	// resolved is returned. Resolvers that ignore their context are not cut short.
+	Timeout time.Duration
+
+	// MaskInternalErrors replaces the message of internal errors, those raised
+	// by resolvers without an apperrors code, with a generic one. Internal errors
+	// are always logged with a correlation ID that is added to their extensions.
+	// Neither happens when FormatErrorFn is set, which then formats every error.
+	MaskInternalErrors bool
+
+	// TracerProvider enables tracing: every executed operation gets a span named
+	// after it, with its type and the hash of its document, and the fields with
+	// resolvers of their own get child spans. A W3C traceparent header sent with
+	// the request becomes the parent of the operation. Nil disables tracing.
+	TracerProvider trace.TracerProvider
+
+	// Metrics, when set, records the operations received over HTTP with their
+	// latency and errors, the latency of resolvers and the requests in flight.
+	Metrics *Metrics
+
+	// ApolloTracing adds the timings of every operation to the `tracing` key of
+	// its extensions in the Apollo Tracing format: when parsing, validation,
+	// execution and each resolver started and how long they took.
+	ApolloTracing bool
+	// ApolloTracingOnRequest adds them only for requests sending an
+	// ApolloTracingHeader, such as those made from GraphiQL while debugging.
+	ApolloTracingOnRequest bool
+
+	// Logger, when set, logs every operation received over HTTP with its name,
+	// type and document hash, its variables, how long it took, the codes of its
+	// errors and the ClientNameHeader and ClientVersionHeader of the request.
+	// Internal errors are logged to it too, and their correlation IDs are added
+	// to the record of their operation.
+	Logger *slog.Logger
+	// RedactVariables names the variables and input fields whose values are
+	// logged as [REDACTED], as are variables passed to an argument or input field
+	// so named. Names match case-insensitively and as part of a longer name.
+	// DefaultRedactedVariables is used when nil.
+	RedactVariables []string
+
+	// FieldUsage, when set, records the fields selected by every operation
+	// executed over HTTP, with its name and the ClientNameHeader and
+	// ClientVersionHeader of the request.
+	FieldUsage *usage.Recorder
 }
 
 func NewConfig() *Config {
@@ -204,6 +551,19 @@ func NewConfig() *Config {
 	}
 }
 
+// handlerExtensions are added to the handler's copy of its schema when any of
+// them is needed. Each finds its configuration in the context of the operation
+// and does nothing without it, so every handler adds all of them in the same
+// order: copies whose extensions share spare capacity write identical values to
+// it. timeoutExtension comes first so the contexts the others derive keep its
+// deadline.
+var handlerExtensions = []graphql.Extension{
+	timeoutExtension{},
+	tracingExtension{},
+	metricsExtension{},
+	apolloTracingExtension{},
+}
+
 func New(p *Config) *Handler {
 	if p == nil {
 		p = NewConfig()
@@ -213,12 +573,65 @@ func New(p *Config) *Handler {
 		panic("undefined GraphQL graphql-definitions")
 	}
 
+	persistedQueries := p.PersistedQueryCache
+	if persistedQueries == nil {
+		persistedQueries = NewLRUPersistedQueryCache(DefaultPersistedQueryCacheSize)
+	}
+
+	schema := *p.Schema
+	if p.Timeout > 0 || p.TracerProvider != nil || p.Metrics != nil || p.ApolloTracing || p.ApolloTracingOnRequest {
+		schema.AddExtensions(handlerExtensions...)
+	}
+
+	var tracer trace.Tracer
+	if p.TracerProvider != nil {
+		tracer = p.TracerProvider.Tracer(TracerName)
+	}
+
+	var logger *operationLogger
+	if p.Logger != nil {
+		logger = newOperationLogger(p.Logger, p.RedactVariables)
+	}
+
+	wsInitTimeout := p.WebSocketInitTimeout
+	if wsInitTimeout <= 0 {
+		wsInitTimeout = DefaultWebSocketInitTimeout
+	}
+	wsReadLimit := p.WebSocketReadLimit
+	if wsReadLimit <= 0 {
+		wsReadLimit = DefaultWebSocketReadLimit
+	}
+
 	return &Handler{
-		Schema:           p.Schema,
+		Schema:           &schema,
 		pretty:           p.Pretty,
 		graphiql:         p.GraphiQL,
 		rootObjectFn:     p.RootObjectFn,
 		resultCallbackFn: p.ResultCallbackFn,
 		formatErrorFn:    p.FormatErrorFn,
+		limits: queryLimits{
+			maxDepth:      p.MaxDepth,
+			maxComplexity: p.MaxComplexity,
+			maxFields:     p.MaxFields,
+			maxAliases:    p.MaxAliases,
+			fieldCosts:    p.FieldCosts,
+		},
+		persistedQueries:       persistedQueries,
+		trustedDocuments:       p.TrustedDocuments,
+		maxBatchSize:           p.MaxBatchSize,
+		batchConcurrency:       p.BatchConcurrency,
+		wsInitFn:               p.WebSocketInitFn,
+		wsInitTimeout:          wsInitTimeout,
+		wsReadLimit:            wsReadLimit,
+		csrfPrevention:         p.CSRFPrevention,
+		maxUploadSize:          p.MaxUploadSize,
+		timeout:                p.Timeout,
+		maskInternalErrors:     p.MaskInternalErrors,
+		tracer:                 tracer,
+		metrics:                p.Metrics,
+		apolloTracing:          p.ApolloTracing,
+		apolloTracingOnRequest: p.ApolloTracingOnRequest,
+		logger:                 logger,
+		fieldUsage:             p.FieldUsage,
 	}
 }
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// queryLimits bounds the shape of an operation before it is executed. A zero
+// value for any maximum disables that check.
+type queryLimits struct {
+	maxDepth      int
+	maxComplexity int
+	maxFields     int
+	maxAliases    int
+	fieldCosts    map[string]int
+}
+
+func (l queryLimits) enabled() bool {
+	return l.maxDepth > 0 || l.maxComplexity > 0 || l.maxFields > 0 || l.maxAliases > 0
+}
+
+// queryMeasure describes the size of one operation.
+type queryMeasure struct {
+	depth      int
+	complexity int
+	fields     int
+	aliases    int
+}
+
+// maxMeasure caps every count so documents that fan out through fragments or
+// page sizes saturate instead of overflowing int.
+const maxMeasure = math.MaxInt32
+
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	costs     map[string]int
+	measure   queryMeasure
+	visiting  map[string]bool
+	// measured holds each fragment's measure, with its depth relative to the
+	// spread, so every fragment is walked only once.
+	measured map[string]queryMeasure
+}
+
+// check measures op and returns a descriptive error when it exceeds a limit.
+// Documents that fail to parse are left for graphql.Do to report.
+func (l queryLimits) check(op *operation) *gqlerrors.FormattedError {
+	if op.definition == nil {
+		return nil
+	}
+	root := operationRootType(&op.params.Schema, op.definition)
+	if root == nil {
+		return nil
+	}
+
+	m := &measurer{
+		schema:    &op.params.Schema,
+		fragments: fragmentDefinitions(op.doc),
+		variables: op.params.VariableValues,
+		costs:     l.fieldCosts,
+		visiting:  map[string]bool{},
+		measured:  map[string]queryMeasure{},
+	}
+	m.measure.complexity, m.measure.depth = m.selectionSet(root, op.definition.SelectionSet, 1)
+
+	exceeded := func(what string, got, max int) *gqlerrors.FormattedError {
+		err := gqlerrors.NewFormattedError(fmt.Sprintf("query %s of %d exceeds the maximum of %d", what, got, max))
+		err.Extensions = map[string]interface{}{"code": "QUERY_TOO_COMPLEX"}
+		return &err
+	}
+	switch {
+	case l.maxDepth > 0 && m.measure.depth > l.maxDepth:
+		return exceeded("depth", m.measure.depth, l.maxDepth)
+	case l.maxFields > 0 && m.measure.fields > l.maxFields:
+		return exceeded("field count", m.measure.fields, l.maxFields)
+	case l.maxAliases > 0 && m.measure.aliases > l.maxAliases:
+		return exceeded("alias count", m.measure.aliases, l.maxAliases)
+	case l.maxComplexity > 0 && m.measure.complexity > l.maxComplexity:
+		return exceeded("complexity", m.measure.complexity, l.maxComplexity)
+	}
+	return nil
+}
+
+// selectionSet returns the complexity and the depth of set, selected on parent
+// at the given depth. Introspection fields are free so tools like GraphiQL keep working.
+func (m *measurer) selectionSet(parent graphql.Type, set *ast.SelectionSet, depth int) (complexity, maxDepth int) {
+	if set == nil {
+		return 0, depth - 1
+	}
+	maxDepth = depth - 1
+
+	for _, selection := range set.Selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c, d = m.field(parent, selection, depth)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				if t := m.schema.Type(selection.TypeCondition.Name.Value); t != nil {
+					fragmentType = t
+				}
+			}
+			c, d = m.selectionSet(fragmentType, selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			c, d = m.fragment(parent, selection.Name.Value, depth)
+		}
+		complexity = addCapped(complexity, c)
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	return complexity, maxDepth
+}
+
+// fragment returns the complexity and the depth of the named fragment spread at
+// the given depth, and adds its fields and aliases to the measure. Fragments
+// spread into themselves count for nothing; validation rejects them later.
+func (m *measurer) fragment(parent graphql.Type, name string, depth int) (complexity, maxDepth int) {
+	fragment, ok := m.fragments[name]
+	if !ok || m.visiting[name] {
+		return 0, depth - 1
+	}
+
+	measured, ok := m.measured[name]
+	if !ok {
+		fragmentType := parent
+		if fragment.TypeCondition != nil {
+			if t := m.schema.Type(fragment.TypeCondition.Name.Value); t != nil {
+				fragmentType = t
+			}
+		}
+		outer := m.measure
+		m.measure = queryMeasure{}
+		m.visiting[name] = true
+		m.measure.complexity, m.measure.depth = m.selectionSet(fragmentType, fragment.SelectionSet, 1)
+		m.visiting[name] = false
+		measured, m.measure = m.measure, outer
+		m.measured[name] = measured
+	}
+
+	m.measure.fields = addCapped(m.measure.fields, measured.fields)
+	m.measure.aliases = addCapped(m.measure.aliases, measured.aliases)
+	return measured.complexity, measured.depth + depth - 1
+}
+
+func (m *measurer) field(parent graphql.Type, field *ast.Field, depth int) (complexity, maxDepth int) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0, depth - 1
+	}
+
+	m.measure.fields = addCapped(m.measure.fields, 1)
+	if field.Alias != nil && field.Alias.Value != name {
+		m.measure.aliases = addCapped(m.measure.aliases, 1)
+	}
+
+	var fieldType graphql.Type
+	cost := 1
+	if parentName, def := m.fieldDefinition(parent, name); def != nil {
+		fieldType, _ = graphql.GetNamed(def.Type).(graphql.Type)
+		if hint, ok := m.costs[parentName+"."+name]; ok {
+			cost = hint
+		}
+	}
+
+	children, maxDepth := m.selectionSet(fieldType, field.SelectionSet, depth+1)
+	return addCapped(cost, mulCapped(m.multiplier(field), children)), maxDepth
+}
+
+func (m *measurer) fieldDefinition(parent graphql.Type, name string) (string, *graphql.FieldDefinition) {
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		return parent.Name(), parent.Fields()[name]
+	case *graphql.Interface:
+		return parent.Name(), parent.Fields()[name]
+	}
+	return "", nil
+}
+
+// multiplier is the page size requested through a `first` or `last` argument,
+// which scales the cost of everything selected below the field.
+func (m *measurer) multiplier(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" && arg.Name.Value != "last" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return capped(n)
+			}
+		case *ast.Variable:
+			switch n := m.variables[value.Name.Value].(type) {
+			case int:
+				if n > 0 {
+					return capped(n)
+				}
+			case float64:
+				if n > 0 {
+					return int(math.Min(n, maxMeasure))
+				}
+			}
+		}
+	}
+	return 1
+}
+
+func capped(n int) int {
+	if n > maxMeasure {
+		return maxMeasure
+	}
+	return n
+}
+
+func addCapped(a, b int) int {
+	if a > maxMeasure-b {
+		return maxMeasure
+	}
+	return a + b
+}
+
+func mulCapped(a, b int) int {
+	if b != 0 && a > maxMeasure/b {
+		return maxMeasure
+	}
+	return a * b
+}
@@ -0,0 +1,139 @@
+package handler_test
+
+import (
+	"fmt"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const nestedFriendsQuery = `query { hero { friends { friends { name } } } }`
+
+func TestHandler_QueryLimits(t *testing.T) {
+	cases := map[string]struct {
+		config   handler.Config
+		query    string
+		expected string
+	}{
+		"depth within limit": {
+			config: handler.Config{MaxDepth: 4},
+			query:  nestedFriendsQuery,
+		},
+		"depth exceeded": {
+			config:   handler.Config{MaxDepth: 3},
+			query:    nestedFriendsQuery,
+			expected: "query depth of 4 exceeds the maximum of 3",
+		},
+		"depth through fragments": {
+			config:   handler.Config{MaxDepth: 3},
+			query:    `query { hero { ...F } } fragment F on Character { friends { ... on Human { friends { name } } } }`,
+			expected: "query depth of 4 exceeds the maximum of 3",
+		},
+		"introspection is not counted": {
+			config: handler.Config{MaxDepth: 2},
+			query:  `query { __schema { types { fields { type { ofType { name } } } } } }`,
+		},
+		"field count exceeded": {
+			config:   handler.Config{MaxFields: 3},
+			query:    nestedFriendsQuery,
+			expected: "query field count of 4 exceeds the maximum of 3",
+		},
+		"alias count exceeded": {
+			config:   handler.Config{MaxAliases: 1},
+			query:    `query { a: hero { name } b: hero { name } }`,
+			expected: "query alias count of 2 exceeds the maximum of 1",
+		},
+		"complexity within limit": {
+			config: handler.Config{MaxComplexity: 4},
+			query:  nestedFriendsQuery,
+		},
+		"complexity with cost hints": {
+			config: handler.Config{
+				MaxComplexity: 11,
+				FieldCosts:    map[string]int{"Character.friends": 5},
+			},
+			query:    nestedFriendsQuery,
+			expected: "query complexity of 12 exceeds the maximum of 11",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := tc.config
+			config.Schema = &testutil.StarWarsSchema
+			h := handler.New(&config)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/graphql?query=%v", url.QueryEscape(tc.query)), nil)
+			result, _ := executeTest(t, h, req)
+
+			if tc.expected == "" {
+				if result.HasErrors() {
+					t.Fatalf("unexpected errors: %v", result.Errors)
+				}
+				return
+			}
+			if len(result.Errors) != 1 || result.Errors[0].Message != tc.expected {
+				t.Fatalf("expected error %q, got %v", tc.expected, result.Errors)
+			}
+			if result.Errors[0].Extensions["code"] != "QUERY_TOO_COMPLEX" {
+				t.Fatalf("expected QUERY_TOO_COMPLEX code, got %v", result.Errors[0].Extensions)
+			}
+			if result.Data != nil {
+				t.Fatalf("query should not have been executed, got data %v", result.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_QueryLimits_PageSizeMultiplier(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, MaxComplexity: 5})
+
+	// `first` does not exist in the Star Wars schema, so execution would fail
+	// validation; the limit must reject the query before that happens.
+	query := `query($n: Int) { hero(first: $n) { name friends { name } } }`
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/graphql?query=%v&variables=%v",
+		url.QueryEscape(query), url.QueryEscape(`{"n": 3}`)), nil)
+	result, _ := executeTest(t, h, req)
+
+	expected := "query complexity of 10 exceeds the maximum of 5"
+	if len(result.Errors) != 1 || result.Errors[0].Message != expected {
+		t.Fatalf("expected error %q, got %v", expected, result.Errors)
+	}
+}
+
+func TestHandler_QueryLimits_FragmentFanOut(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, MaxFields: 100})
+
+	// Each fragment spreads the next one twice, so the document selects 2^22
+	// names; measuring it must not walk every one of them.
+	var b strings.Builder
+	b.WriteString(`query { hero { ...F0 } }`)
+	for i := 0; i < 22; i++ {
+		fmt.Fprintf(&b, " fragment F%d on Character { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	b.WriteString(" fragment F22 on Character { name }")
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(b.String()))
+	req.Header.Set("Content-Type", "application/graphql")
+	result, _ := executeTest(t, h, req)
+
+	expected := "query field count of 4194305 exceeds the maximum of 100"
+	if len(result.Errors) != 1 || result.Errors[0].Message != expected {
+		t.Fatalf("expected error %q, got %v", expected, result.Errors)
+	}
+}
+
+func TestHandler_QueryLimits_PageSizeOverflow(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, MaxComplexity: 1000})
+
+	query := `query { hero(first: 9223372036854775807) { friends(first: 9223372036854775807) { friends(last: 9223372036854775807) { name } } } }`
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/graphql?query=%v", url.QueryEscape(query)), nil)
+	result, _ := executeTest(t, h, req)
+
+	expected := "query complexity of 2147483647 exceeds the maximum of 1000"
+	if len(result.Errors) != 1 || result.Errors[0].Message != expected {
+		t.Fatalf("expected error %q, got %v", expected, result.Errors)
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"golang.org/x/exp/slog"
+)
+
+// Headers naming the client that sent a request and its version, as sent by
+// Apollo Client.
+const (
+	ClientNameHeader    = "Apollographql-Client-Name"
+	ClientVersionHeader = "Apollographql-Client-Version"
+)
+
+// DefaultRedactedVariables are the variable names redacted from logs when
+// Config.RedactVariables is nil.
+var DefaultRedactedVariables = []string{"password", "secret", "token", "authorization", "apikey", "credential"}
+
+// redacted replaces the values of sensitive variables in logs.
+const redacted = "[REDACTED]"
+
+// operationLogger logs every operation received over HTTP.
+type operationLogger struct {
+	logger *slog.Logger
+	redact []string
+}
+
+func newOperationLogger(logger *slog.Logger, redactVariables []string) *operationLogger {
+	if redactVariables == nil {
+		redactVariables = DefaultRedactedVariables
+	}
+	redact := make([]string, len(redactVariables))
+	for i, name := range redactVariables {
+		redact[i] = normalizeVariableName(name)
+	}
+	return &operationLogger{logger: logger, redact: redact}
+}
+
+// log records op, an operation of r answered with result, with the correlation
+// IDs of its internal errors. Operations failing with internal errors are
+// logged as errors and those with other errors as warnings.
+func (l *operationLogger) log(r *http.Request, op *operation, opType, opName string,
+	result *graphql.Result, duration time.Duration) {
+	params := op.params
+	level := slog.LevelInfo
+	codes := make([]string, len(result.Errors))
+	for i, e := range result.Errors {
+		codes[i] = errorCode(e)
+		if codes[i] == "INTERNAL" {
+			level = slog.LevelError
+		} else if level < slog.LevelWarn {
+			level = slog.LevelWarn
+		}
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation_name", opName),
+		slog.String("operation_type", opType),
+		slog.String("query_hash", documentHash(params.RequestString)),
+		slog.Any("variables", l.redactVariables(op)),
+		slog.Float64("duration_ms", float64(duration)/float64(time.Millisecond)),
+		slog.Any("error_codes", codes),
+	}
+	var correlationIDs []string
+	for _, e := range result.Errors {
+		if id, ok := e.Extensions["correlationId"].(string); ok {
+			correlationIDs = append(correlationIDs, id)
+		}
+	}
+	if len(correlationIDs) > 0 {
+		attrs = append(attrs, slog.Any("correlation_ids", correlationIDs))
+	}
+	if name := r.Header.Get(ClientNameHeader); name != "" {
+		attrs = append(attrs, slog.String("client_name", name))
+	}
+	if version := r.Header.Get(ClientVersionHeader); version != "" {
+		attrs = append(attrs, slog.String("client_version", version))
+	}
+	l.logger.LogAttrs(params.Context, level, "graphql operation", attrs...)
+}
+
+// redactVariables copies the variables of op, replacing those that are
+// sensitive by their own name or by the name of an argument or input field of
+// op they are passed to, so that `$key` is redacted when given as `apiKey`.
+func (l *operationLogger) redactVariables(op *operation) map[string]interface{} {
+	bindings := variableBindings(op)
+	variables := make(map[string]interface{}, len(op.params.VariableValues))
+	for name, value := range op.params.VariableValues {
+		variables[name] = l.redactValue(name, value)
+		for _, bound := range bindings[name] {
+			if l.sensitive(bound) {
+				variables[name] = redacted
+				break
+			}
+		}
+	}
+	return variables
+}
+
+// redactValue copies the variable value under key, replacing it when key is
+// sensitive and the values of sensitive fields of input objects and lists.
+// Uploaded files are described by their name and size.
+func (l *operationLogger) redactValue(key string, value interface{}) interface{} {
+	if key != "" && l.sensitive(key) {
+		return redacted
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			copied[k] = l.redactValue(k, v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, v := range value {
+			copied[i] = l.redactValue("", v)
+		}
+		return copied
+	case *multipart.FileHeader:
+		return map[string]interface{}{"filename": value.Filename, "size": value.Size}
+	default:
+		return value
+	}
+}
+
+// sensitive reports whether a variable or input field called key contains one
+// of the redacted names, so that `password` also covers `newPassword`.
+func (l *operationLogger) sensitive(key string) bool {
+	key = normalizeVariableName(key)
+	for _, name := range l.redact {
+		if strings.Contains(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeVariableName folds the case and separators of name, making
+// api_key, apiKey and API-KEY the same.
+func normalizeVariableName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// variableBindings maps the variables used by op to the names of the
+// arguments and input fields they are passed to, in op and the fragments it
+// spreads.
+func variableBindings(op *operation) map[string][]string {
+	bindings := map[string][]string{}
+	if op.definition == nil {
+		return bindings
+	}
+	fragments := fragmentDefinitions(op.doc)
+	visiting := map[string]bool{}
+
+	var bindValue func(name string, value ast.Value)
+	bindValue = func(name string, value ast.Value) {
+		switch value := value.(type) {
+		case *ast.Variable:
+			bindings[value.Name.Value] = append(bindings[value.Name.Value], name)
+		case *ast.ObjectValue:
+			for _, field := range value.Fields {
+				bindValue(field.Name.Value, field.Value)
+			}
+		case *ast.ListValue:
+			for _, item := range value.Values {
+				bindValue(name, item)
+			}
+		}
+	}
+	bindArguments := func(arguments []*ast.Argument, directives []*ast.Directive) {
+		for _, directive := range directives {
+			arguments = append(arguments, directive.Arguments...)
+		}
+		for _, argument := range arguments {
+			bindValue(argument.Name.Value, argument.Value)
+		}
+	}
+	var bindSelectionSet func(set *ast.SelectionSet)
+	bindSelectionSet = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				bindArguments(selection.Arguments, selection.Directives)
+				bindSelectionSet(selection.SelectionSet)
+			case *ast.InlineFragment:
+				bindArguments(nil, selection.Directives)
+				bindSelectionSet(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				bindArguments(nil, selection.Directives)
+				name := selection.Name.Value
+				if fragment, ok := fragments[name]; ok && !visiting[name] {
+					visiting[name] = true
+					bindSelectionSet(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	bindArguments(nil, op.definition.Directives)
+	bindSelectionSet(op.definition.SelectionSet)
+	return bindings
+}
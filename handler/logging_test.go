@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// loginSchema has a `login` mutation taking credentials as arguments and as an
+// input object, and a `broken` query failing with an internal error.
+func loginSchema(t *testing.T) *graphql.Schema {
+	credentials := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Credentials",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"email":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"newPassword": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"ok": &graphql.Field{Type: graphql.Boolean},
+				"broken": &graphql.Field{
+					Type: graphql.Boolean,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, errors.New("disk on fire")
+					},
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"login": &graphql.Field{
+					Type: graphql.Boolean,
+					Args: graphql.FieldConfigArgument{
+						"apiKey":      &graphql.ArgumentConfig{Type: graphql.String},
+						"credentials": &graphql.ArgumentConfig{Type: graphql.NewList(credentials)},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return true, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func loggedOperation(t *testing.T, config *handler.Config, body string, header http.Header) map[string]interface{} {
+	var logs bytes.Buffer
+	config.Logger = slog.New(slog.NewJSONHandler(&logs))
+	h := handler.New(config)
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", logs.String(), err)
+	}
+	return record
+}
+
+func TestHandler_Logger(t *testing.T) {
+	header := http.Header{}
+	header.Set(handler.ClientNameHeader, "web")
+	header.Set(handler.ClientVersionHeader, "1.2.3")
+	record := loggedOperation(t, &handler.Config{Schema: loginSchema(t)}, `{
+		"query": "mutation Login($key: String, $creds: [Credentials]) { login(apiKey: $key, credentials: $creds) }",
+		"variables": {"key": "k", "API_KEY": "k", "creds": [{"email": "a@example.com", "newPassword": "hunter2"}]}
+	}`, header)
+
+	for key, want := range map[string]interface{}{
+		"level":          "INFO",
+		"msg":            "graphql operation",
+		"operation_name": "Login",
+		"operation_type": "mutation",
+		"client_name":    "web",
+		"client_version": "1.2.3",
+	} {
+		if record[key] != want {
+			t.Errorf("%s = %v, want %v", key, record[key], want)
+		}
+	}
+	if hash, _ := record["query_hash"].(string); len(hash) != 64 {
+		t.Errorf("query_hash = %v", record["query_hash"])
+	}
+	if _, ok := record["duration_ms"].(float64); !ok {
+		t.Errorf("duration_ms = %v", record["duration_ms"])
+	}
+	if codes, _ := record["error_codes"].([]interface{}); len(codes) != 0 {
+		t.Errorf("error_codes = %v", record["error_codes"])
+	}
+
+	// $key and $creds are redacted for the arguments they are passed to
+	variables, _ := json.Marshal(record["variables"])
+	if want := `{"API_KEY":"[REDACTED]","creds":"[REDACTED]","key":"[REDACTED]"}`; string(variables) != want {
+		t.Errorf("variables = %s, want %s", variables, want)
+	}
+}
+
+func TestHandler_LoggerRedactVariables(t *testing.T) {
+	record := loggedOperation(t, &handler.Config{Schema: loginSchema(t), RedactVariables: []string{"email"}}, `{
+		"query": "mutation($creds: [Credentials], $to: String) { login(credentials: $creds) ...again } fragment again on Mutation { login(credentials: [{email: $to}]) }",
+		"variables": {"creds": [{"email": "a@example.com", "newPassword": "hunter2"}], "to": "b@example.com"}
+	}`, http.Header{})
+
+	variables, _ := json.Marshal(record["variables"])
+	if want := `{"creds":[{"email":"[REDACTED]","newPassword":"hunter2"}],"to":"[REDACTED]"}`; string(variables) != want {
+		t.Errorf("variables = %s, want %s", variables, want)
+	}
+	if _, ok := record["client_name"]; ok {
+		t.Error("client_name was logged without the header")
+	}
+}
+
+func TestHandler_LoggerErrors(t *testing.T) {
+	record := loggedOperation(t, &handler.Config{Schema: loginSchema(t)},
+		`{"query": "{ ok missing }"}`, http.Header{})
+
+	if record["level"] != "WARN" {
+		t.Errorf("level = %v, want WARN", record["level"])
+	}
+	if codes, _ := record["error_codes"].([]interface{}); len(codes) != 1 || codes[0] != "UNKNOWN" {
+		t.Errorf("error_codes = %v", record["error_codes"])
+	}
+}
+
+func TestHandler_LoggerInternalErrors(t *testing.T) {
+	var logs bytes.Buffer
+	h := handler.New(&handler.Config{Schema: loginSchema(t), Logger: slog.New(slog.NewJSONHandler(&logs))})
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ ok broken }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var result struct {
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || len(result.Errors) != 1 {
+		t.Fatalf("expected one error, got %s", rr.Body)
+	}
+	id := result.Errors[0].Extensions["correlationId"]
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected JSON records, got %q: %v", logs.String(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the error and the operation to be logged, got %v", records)
+	}
+	if internal := records[0]; internal["level"] != "ERROR" || internal["correlation_id"] != id || internal["error"] != "disk on fire" {
+		t.Errorf("unexpected internal error record %v", internal)
+	}
+	if ids, _ := records[1]["correlation_ids"].([]interface{}); len(ids) != 1 || ids[0] != id {
+		t.Errorf("correlation_ids = %v, want [%v]", records[1]["correlation_ids"], id)
+	}
+}
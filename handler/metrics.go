@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherOperationName is the operation_name label of operations whose document
+// was sent by the client rather than taken from TrustedDocuments or the
+// persisted query cache.
+const otherOperationName = "other"
+
+// Metrics records the traffic of a Handler as Prometheus metrics.
+type Metrics struct {
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+	resolvers *prometheus.HistogramVec
+	inFlight  *prometheus.GaugeVec
+}
+
+// NewMetrics creates the metrics of a Handler and registers them with registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "graphql",
+			Name:      "requests_total",
+			Help:      "Operations received over HTTP, by operation name and type.",
+		}, []string{"operation_name", "operation_type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "graphql",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to answer operations received over HTTP, by operation name and type.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation_name", "operation_type"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "graphql",
+			Name:      "errors_total",
+			Help:      "Errors returned to clients, by the code in their extensions.",
+		}, []string{"code"}),
+		resolvers: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "graphql",
+			Name:      "resolver_duration_seconds",
+			Help:      "Time taken by the resolvers of fields, by parent type and field.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9),
+		}, []string{"parent_type", "field"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "graphql",
+			Name:      "requests_in_flight",
+			Help:      "Requests being served, by transport: http, sse or websocket.",
+		}, []string{"transport"}),
+	}
+	for _, collector := range []prometheus.Collector{m.requests, m.duration, m.errors, m.resolvers, m.inFlight} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// track counts r as in flight until the returned function is called.
+func (m *Metrics) track(r *http.Request) func() {
+	transport := "http"
+	if websocket.IsWebSocketUpgrade(r) {
+		transport = "websocket"
+	} else if acceptsEventStream(r) {
+		transport = "sse"
+	}
+	gauge := m.inFlight.WithLabelValues(transport)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// observe records an operation answered with result, including any rejected
+// before execution, and the errors it returned.
+func (m *Metrics) observe(opType, opName string, result *graphql.Result, duration time.Duration) {
+	m.requests.WithLabelValues(opName, opType).Inc()
+	m.duration.WithLabelValues(opName, opType).Observe(duration.Seconds())
+	for _, e := range result.Errors {
+		m.errors.WithLabelValues(errorCode(e)).Inc()
+	}
+}
+
+// errorCode returns the code in the extensions of e, or UNKNOWN without one.
+func errorCode(e gqlerrors.FormattedError) string {
+	if code, ok := e.Extensions["code"].(string); ok && code != "" {
+		return code
+	}
+	return "UNKNOWN"
+}
+
+// metricsKey carries the Metrics of the handler executing an operation.
+type metricsKey struct{}
+
+// metricsExtension times the fields with a resolver of their own in the
+// Metrics of the handler executing them.
+type metricsExtension struct{}
+
+var _ graphql.Extension = metricsExtension{}
+
+func (e metricsExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (e metricsExtension) Name() string {
+	return "prometheus"
+}
+
+func (e metricsExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e metricsExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e metricsExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (e metricsExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	metrics, ok := ctx.Value(metricsKey{}).(*Metrics)
+	if !ok || !hasResolver(info) {
+		return ctx, func(interface{}, error) {}
+	}
+	start := time.Now()
+	observer := metrics.resolvers.WithLabelValues(info.ParentType.Name(), info.FieldName)
+	return ctx, func(interface{}, error) {
+		observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (e metricsExtension) HasResult() bool {
+	return false
+}
+
+func (e metricsExtension) GetResult(context.Context) interface{} {
+	return nil
+}
@@ -0,0 +1,170 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chalkedgoose/act-up-api/apperrors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// metricValue returns the value of the counter or gauge, or the sample count of
+// the histogram, called name with labels in registry.
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				return metric.Gauge.GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := handler.NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inFlight float64
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"inFlight": &graphql.Field{
+					Type: graphql.Float,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						inFlight = metricValue(t, registry, "graphql_requests_in_flight", map[string]string{"transport": "http"})
+						return inFlight, nil
+					},
+				},
+				"missing": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, apperrors.New(apperrors.NotFound, "missing")
+					},
+				},
+				"broken": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, errors.New("broken")
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(&handler.Config{Schema: &schema, Metrics: metrics, MaxBatchSize: 5})
+	persisted := "query P { inFlight }"
+
+	for _, body := range []string{
+		`{"query": "query Q { inFlight missing }"}`,
+		`[{"query": "query Q { inFlight }"}, {"query": "query B { broken }"}]`,
+		`{"query": "{ unknown }"}`,
+		// documents sent by clients do not label metrics with the names they chose
+		`{"query": "query Made { unknown }"}`,
+		`{"query": "{ unknown", "operationName": "Up"}`,
+		// registering a persisted query, then running it from the cache
+		fmt.Sprintf(`{"query": %q, "extensions": {"persistedQuery": {"version": 1, "sha256Hash": %q}}}`, persisted, sha256Hex(persisted)),
+		fmt.Sprintf(`{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": %q}}}`, sha256Hex(persisted)),
+	} {
+		req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if inFlight != 1 {
+		t.Fatalf("%v requests in flight while resolving", inFlight)
+	}
+	for _, want := range []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{"graphql_requests_total", map[string]string{"operation_name": "other", "operation_type": "query"}, 6},
+		{"graphql_requests_total", map[string]string{"operation_name": "other", "operation_type": ""}, 1},
+		{"graphql_requests_total", map[string]string{"operation_name": "P", "operation_type": "query"}, 1},
+		{"graphql_requests_total", map[string]string{"operation_name": "Q", "operation_type": "query"}, 0},
+		{"graphql_requests_total", map[string]string{"operation_name": "Made", "operation_type": "query"}, 0},
+		{"graphql_requests_total", map[string]string{"operation_name": "Up", "operation_type": ""}, 0},
+		{"graphql_request_duration_seconds", map[string]string{"operation_name": "other", "operation_type": "query"}, 6},
+		{"graphql_errors_total", map[string]string{"code": "NOT_FOUND"}, 1},
+		{"graphql_errors_total", map[string]string{"code": "INTERNAL"}, 1},
+		{"graphql_errors_total", map[string]string{"code": "UNKNOWN"}, 3},
+		{"graphql_resolver_duration_seconds", map[string]string{"parent_type": "Query", "field": "inFlight"}, 4},
+		{"graphql_resolver_duration_seconds", map[string]string{"parent_type": "Query", "field": "broken"}, 1},
+		{"graphql_requests_in_flight", map[string]string{"transport": "http"}, 0},
+	} {
+		if got := metricValue(t, registry, want.name, want.labels); got != want.value {
+			t.Errorf("%s%v = %v, want %v", want.name, want.labels, got, want.value)
+		}
+	}
+}
+
+func TestNewMetrics_AlreadyRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := handler.NewMetrics(registry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.NewMetrics(registry); err == nil {
+		t.Fatal("registering the metrics twice succeeded")
+	}
+}
+
+func TestHandler_MetricsTrustedDocuments(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := handler.NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(&handler.Config{
+		Schema:           &testutil.StarWarsSchema,
+		Metrics:          metrics,
+		TrustedDocuments: handler.NewTrustedDocuments(map[string]string{"hero": "query Hero { hero { name } }"}),
+	})
+
+	for _, body := range []string{
+		`{"documentId": "hero"}`,
+		`{"query": "query Hero { hero { name } }"}`,
+		`{"query": "query Villain { hero { name } }"}`,
+	} {
+		req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for name, want := range map[string]float64{"Hero": 2, "Villain": 0, "other": 1} {
+		got := metricValue(t, registry, "graphql_requests_total", map[string]string{"operation_name": name, "operation_type": "query"})
+		if got != want {
+			t.Errorf("graphql_requests_total{operation_name=%q} = %v, want %v", name, got, want)
+		}
+	}
+}
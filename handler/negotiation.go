@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// ContentTypeGraphQLResponse is the media type of the GraphQL over HTTP spec.
+// Clients accepting it get status codes reflecting request errors; clients that
+// only accept ContentTypeJSON keep receiving 200 for every GraphQL response.
+const ContentTypeGraphQLResponse = "application/graphql-response+json"
+
+// negotiateMediaType picks the response media type for an Accept header, or
+// returns "" when the client accepts neither JSON media type. A missing header
+// or a wildcard selects ContentTypeJSON, as legacy clients expect.
+func negotiateMediaType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return ContentTypeJSON
+	}
+
+	var best string
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case ContentTypeGraphQLResponse:
+			candidate = ContentTypeGraphQLResponse
+		case ContentTypeJSON, "application/*", "*/*":
+			candidate = ContentTypeJSON
+		default:
+			continue
+		}
+		// the spec media type wins ties, it was named explicitly
+		if q > bestQ || (q == bestQ && q > 0 && candidate == ContentTypeGraphQLResponse) {
+			best, bestQ = candidate, q
+		}
+	}
+	return best
+}
+
+// supportedContentType reports whether the body of r can be parsed by
+// NewRequestOptions. A missing Content-Type is read as JSON, and multipart
+// bodies are only accepted when uploads are enabled.
+func (h *Handler) supportedContentType(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return true
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case ContentTypeJSON, ContentTypeGraphQL, ContentTypeFormURLEncoded:
+		return true
+	case ContentTypeMultipartFormData:
+		return h.maxUploadSize > 0
+	}
+	return false
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return r.Method == http.MethodPost && mediaType == ContentTypeMultipartFormData
+}
+
+// refuseGET returns why op may not be executed for a GET request r, which is
+// only allowed to run the given operation types.
+func refuseGET(r *http.Request, op *operation, allowed ...string) string {
+	if r.Method != http.MethodGet {
+		return ""
+	}
+	opType, _ := op.describe()
+	if opType == "" {
+		return ""
+	}
+	for _, allowedType := range allowed {
+		if opType == allowedType {
+			return ""
+		}
+	}
+	return opType + " operations cannot be sent with GET, use POST"
+}
+
+// failedBeforeExecution reports whether graphql.Do rejected the document of
+// result before executing it: it did not parse or validate, or its variables
+// could not be coerced. An error nulling a non-null root field also leaves no
+// data, but it was raised by that field and carries its path.
+func failedBeforeExecution(result *graphql.Result) bool {
+	if result.Data != nil || len(result.Errors) == 0 {
+		return false
+	}
+	for _, e := range result.Errors {
+		if len(e.Path) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// resultStatus is the status code of a response. Request errors, raised before
+// execution began, are reported as bad requests in the spec media type.
+func resultStatus(mediaType string, requestErr bool) int {
+	if mediaType == ContentTypeGraphQLResponse && requestErr {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// requestErrorResponse is the body of a request error in the spec media type,
+// which must not contain a `data` entry.
+type requestErrorResponse struct {
+	Errors     []gqlerrors.FormattedError `json:"errors"`
+	Extensions map[string]interface{}     `json:"extensions,omitempty"`
+}
+
+// responseBody returns what is written for result in mediaType, requestErr
+// telling whether it is a request error.
+func responseBody(mediaType string, result *graphql.Result, requestErr bool) interface{} {
+	if mediaType == ContentTypeGraphQLResponse && requestErr {
+		return &requestErrorResponse{Errors: result.Errors, Extensions: result.Extensions}
+	}
+	return result
+}
+
+// writeRequestError answers a request that is rejected before its operation is
+// looked at with a single BAD_REQUEST error.
+func (h *Handler) writeRequestError(w http.ResponseWriter, mediaType string, status int, message string) {
+	result := requestError(message, "BAD_REQUEST")
+	h.writeJSON(w, mediaType, status, &requestErrorResponse{Errors: result.Errors})
+}
+
+func requestError(message, code string) *graphql.Result {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": code}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{err}}
+}
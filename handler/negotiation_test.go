@@ -0,0 +1,226 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// counterSchema has an `increment` mutation next to a `count` query and a
+// non-null `broken` query that always fails.
+func counterSchema(t *testing.T) *graphql.Schema {
+	count := 0
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"count": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return count, nil
+					},
+				},
+				"broken": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, errors.New("broken")
+					},
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"increment": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						count++
+						return count, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestHandler_StatusCodes(t *testing.T) {
+	const graphqlResponse = "application/graphql-response+json"
+	cases := map[string]struct {
+		method              string
+		url                 string
+		contentType         string
+		accept              string
+		body                string
+		expectedStatusCode  int
+		expectedContentType string
+		expectData          bool
+	}{
+		"legacy clients get 200 for parse errors": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              "application/json",
+			body:                `{"query": "{ count"}`,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectData:          true,
+		},
+		"parse errors are bad requests": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse,
+			body:                `{"query": "{ count"}`,
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+		},
+		"validation errors are bad requests": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse + ", application/json;q=0.9",
+			body:                `{"query": "{ unknown }"}`,
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+		},
+		"executed operations are ok": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse,
+			body:                `{"query": "{ count }"}`,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+			expectData:          true,
+		},
+		"execution errors nulling the data are ok": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse,
+			body:                `{"query": "{ count broken }"}`,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+			expectData:          true,
+		},
+		"variable errors are bad requests": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse,
+			body:                `{"query": "query($b: Boolean!) { broken @include(if: $b) }"}`,
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+		},
+		"a preferred application/json wins": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              graphqlResponse + ";q=0.5, application/json",
+			body:                `{"query": "{ count"}`,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectData:          true,
+		},
+		"mutations over GET are not allowed": {
+			method:              http.MethodGet,
+			url:                 "?query=mutation{increment}",
+			accept:              graphqlResponse,
+			expectedStatusCode:  http.StatusMethodNotAllowed,
+			expectedContentType: graphqlResponse + "; charset=utf-8",
+		},
+		"legacy clients cannot send mutations over GET either": {
+			method:              http.MethodGet,
+			url:                 "?query=mutation{increment}",
+			accept:              "application/json",
+			expectedStatusCode:  http.StatusMethodNotAllowed,
+			expectedContentType: "application/json; charset=utf-8",
+		},
+		"queries over GET are ok": {
+			method:              http.MethodGet,
+			url:                 "?query=query%20Q{count}mutation%20M{increment}&operationName=Q",
+			accept:              "application/json",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectData:          true,
+		},
+		"unacceptable media types": {
+			method:              http.MethodPost,
+			contentType:         "application/json",
+			accept:              "application/xml",
+			body:                `{"query": "{ count }"}`,
+			expectedStatusCode:  http.StatusNotAcceptable,
+			expectedContentType: "application/json; charset=utf-8",
+		},
+		"unsupported content types": {
+			method:              http.MethodPost,
+			contentType:         "application/xml",
+			accept:              graphqlResponse,
+			body:                `<query>{ count }</query>`,
+			expectedStatusCode:  http.StatusUnsupportedMediaType,
+			expectedContentType: "application/json; charset=utf-8",
+		},
+	}
+
+	for tcID, tc := range cases {
+		t.Run(tcID, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/graphql"+tc.url, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			req.Header.Set("Accept", tc.accept)
+
+			h := handler.New(&handler.Config{Schema: counterSchema(t)})
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatusCode {
+				t.Fatalf("wrong status code, expected %v, got %v: %s", tc.expectedStatusCode, rr.Code, rr.Body)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tc.expectedContentType {
+				t.Fatalf("wrong content type, expected %s, got %s", tc.expectedContentType, contentType)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if _, hasData := body["data"]; hasData != tc.expectData {
+				t.Fatalf("expected data entry %v in %s", tc.expectData, rr.Body)
+			}
+			if tc.expectedStatusCode == http.StatusMethodNotAllowed && rr.Header().Get("Allow") != http.MethodPost {
+				t.Fatalf("expected Allow: POST, got %q", rr.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestHandler_StatusCodes_Batch(t *testing.T) {
+	body := `[{"query": "{ hero { name } }"}, {"query": "{ hero"}]`
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json")
+
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, MaxBatchSize: 2})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected server response %v", rr.Code)
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if _, hasData := results[0]["data"]; !hasData {
+		t.Fatalf("expected data in the first result: %s", rr.Body)
+	}
+	if _, hasData := results[1]["data"]; hasData {
+		t.Fatalf("expected no data in the failed result: %s", rr.Body)
+	}
+}
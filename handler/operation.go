@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// parseQuery parses a request string without validating it against a schema.
+func parseQuery(query string) (*ast.Document, error) {
+	return parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(query),
+			Name: "GraphQL request",
+		}),
+	})
+}
+
+// selectOperation returns the operation graphql.Do would execute for operationName,
+// or nil when the document does not identify exactly one.
+func selectOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var selected *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" {
+			if selected != nil {
+				return nil
+			}
+			selected = op
+		} else if op.Name != nil && op.Name.Value == operationName {
+			return op
+		}
+	}
+	return selected
+}
+
+// fragmentDefinitions indexes the named fragments of doc.
+func fragmentDefinitions(doc *ast.Document) map[string]*ast.FragmentDefinition {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return fragments
+}
+
+// operationRootType returns the schema type an operation of op's kind starts from.
+func operationRootType(schema *graphql.Schema, op *ast.OperationDefinition) *graphql.Object {
+	switch op.Operation {
+	case ast.OperationTypeMutation:
+		return schema.MutationType()
+	case ast.OperationTypeSubscription:
+		return schema.SubscriptionType()
+	default:
+		return schema.QueryType()
+	}
+}
+
+// operation is an operation of a request ready to be executed. Its document is
+// parsed once, when it is prepared, for everything that inspects it before
+// graphql.Do executes it.
+type operation struct {
+	params graphql.Params
+	// doc is nil when the request string does not parse.
+	doc *ast.Document
+	// definition is the operation of doc graphql.Do executes, nil when doc does
+	// not identify one.
+	definition *ast.OperationDefinition
+	// persisted is set when the document came from TrustedDocuments or the
+	// persisted query cache instead of the request.
+	persisted bool
+}
+
+func newOperation(params graphql.Params) *operation {
+	op := &operation{params: params}
+	if doc, err := parseQuery(params.RequestString); err == nil {
+		op.doc = doc
+		op.definition = selectOperation(doc, params.OperationName)
+	}
+	return op
+}
+
+// describe returns the type and name of the operation, both empty when the
+// document does not identify one. The name is never taken from
+// params.OperationName alone.
+func (op *operation) describe() (opType, opName string) {
+	if op.definition == nil {
+		return "", ""
+	}
+	if op.definition.Name != nil {
+		opName = op.definition.Name.Value
+	}
+	return op.definition.Operation, opName
+}
+
+// documentHash identifies the document of an operation by the hex encoded
+// SHA-256 hash of its text, as automatic persisted queries do.
+func documentHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
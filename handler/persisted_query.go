@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// PersistedQueryCache stores query documents by the hex encoded SHA-256 hash
+// of their text, for Apollo's automatic persisted queries (APQ) protocol.
+type PersistedQueryCache interface {
+	Get(ctx context.Context, hash string) (query string, ok bool)
+	Add(ctx context.Context, hash string, query string)
+}
+
+// DefaultPersistedQueryCacheSize is the capacity of the cache New installs
+// when Config.PersistedQueryCache is nil.
+const DefaultPersistedQueryCacheSize = 1000
+
+type lruEntry struct {
+	hash  string
+	query string
+}
+
+// lruPersistedQueryCache evicts the least recently used query once full.
+type lruPersistedQueryCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewLRUPersistedQueryCache returns an in-memory cache holding at most size queries.
+func NewLRUPersistedQueryCache(size int) PersistedQueryCache {
+	return &lruPersistedQueryCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *lruPersistedQueryCache) Get(ctx context.Context, hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[hash]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).query, true
+}
+
+func (c *lruPersistedQueryCache) Add(ctx context.Context, hash string, query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[hash]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[hash] = c.order.PushFront(&lruEntry{hash: hash, query: query})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).hash)
+	}
+}
+
+func persistedQueryError(message, code string) *gqlerrors.FormattedError {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": code}
+	return &err
+}
+
+// loadPersistedQuery implements the server side of APQ on opts: a request with
+// only a hash has its query filled in from the cache, and a request with both
+// registers the query after checking the hash. Requests without the extension
+// are left alone. In trusted documents mode only approved queries are
+// registered, so the cache cannot be filled with documents that are rejected.
+func (h *Handler) loadPersistedQuery(ctx context.Context, opts *RequestOptions) *gqlerrors.FormattedError {
+	ext, ok := opts.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if version, _ := ext["version"].(float64); version != 1 {
+		return persistedQueryError("Unsupported persisted query version", "PERSISTED_QUERY_NOT_SUPPORTED")
+	}
+	hash, _ := ext["sha256Hash"].(string)
+	if hash == "" {
+		return persistedQueryError("persistedQuery extension is missing sha256Hash", "BAD_REQUEST")
+	}
+
+	if opts.Query == "" {
+		query, found := h.persistedQueries.Get(ctx, hash)
+		if !found {
+			return persistedQueryError("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND")
+		}
+		opts.Query = query
+		return nil
+	}
+
+	if documentHash(opts.Query) != hash {
+		return persistedQueryError("provided sha does not match query", "BAD_REQUEST")
+	}
+	if docs := h.trustedDocuments; docs != nil && !docs.approved[opts.Query] {
+		// loadTrustedDocument rejects it
+		return nil
+	}
+	h.persistedQueries.Add(ctx, hash, opts.Query)
+	return nil
+}
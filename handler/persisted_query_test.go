@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const heroNameQuery = `query { hero { name } }`
+
+var heroNameResult = &graphql.Result{
+	Data: map[string]interface{}{
+		"hero": map[string]interface{}{"name": "R2-D2"},
+	},
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func persistedQueryRequest(t *testing.T, query, hash string) *http.Request {
+	body := fmt.Sprintf(`{"query": %q, "extensions": {"persistedQuery": {"version": 1, "sha256Hash": %q}}}`, query, hash)
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func expectErrorCode(t *testing.T, result *graphql.Result, code string) {
+	t.Helper()
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != code {
+		t.Fatalf("expected a single %s error, got %v", code, result.Errors)
+	}
+}
+
+func TestHandler_PersistedQuery_RegisterThenHashOnly(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema})
+	hash := sha256Hex(heroNameQuery)
+
+	result, _ := executeTest(t, h, persistedQueryRequest(t, "", hash))
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_FOUND")
+	if result.Errors[0].Message != "PersistedQueryNotFound" {
+		t.Fatalf("unexpected message %q", result.Errors[0].Message)
+	}
+
+	result, _ = executeTest(t, h, persistedQueryRequest(t, heroNameQuery, hash))
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+
+	result, _ = executeTest(t, h, persistedQueryRequest(t, "", hash))
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+}
+
+func TestHandler_PersistedQuery_GET(t *testing.T) {
+	cache := handler.NewLRUPersistedQueryCache(10)
+	hash := sha256Hex(heroNameQuery)
+	cache.Add(context.Background(), hash, heroNameQuery)
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, PersistedQueryCache: cache})
+
+	extensions := fmt.Sprintf(`{"persistedQuery":{"version":1,"sha256Hash":%q}}`, hash)
+	req, _ := http.NewRequest("GET", "/graphql?extensions="+url.QueryEscape(extensions), nil)
+	result, _ := executeTest(t, h, req)
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+}
+
+func TestHandler_PersistedQuery_HashMismatch(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema})
+
+	result, _ := executeTest(t, h, persistedQueryRequest(t, heroNameQuery, sha256Hex("query { other }")))
+	expectErrorCode(t, result, "BAD_REQUEST")
+
+	// a mismatched query must not be registered under the hash
+	result, _ = executeTest(t, h, persistedQueryRequest(t, "", sha256Hex("query { other }")))
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_FOUND")
+}
+
+func TestLRUPersistedQueryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := handler.NewLRUPersistedQueryCache(2)
+	cache.Add(ctx, "a", "query A")
+	cache.Add(ctx, "b", "query B")
+	cache.Get(ctx, "a")
+	cache.Add(ctx, "c", "query C")
+
+	if _, ok := cache.Get(ctx, "b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	for _, hash := range []string{"a", "c"} {
+		if _, ok := cache.Get(ctx, hash); !ok {
+			t.Fatalf("expected %s to be cached", hash)
+		}
+	}
+}
@@ -409,3 +409,24 @@ func TestRequestOptions_POST_UnsupportedContentType(t *testing.T) {
 		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
 	}
 }
+
+func TestRequestOptions_GET_PersistedQueryExtensions(t *testing.T) {
+	queryString := `extensions={"persistedQuery":{"version":1,"sha256Hash":"abc"}}&operationName=Hero`
+	expected := &handler.RequestOptions{
+		Variables:     make(map[string]interface{}),
+		OperationName: "Hero",
+		Extensions: map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    float64(1),
+				"sha256Hash": "abc",
+			},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/graphql?%v", queryString), nil)
+	result := handler.NewRequestOptions(req)
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
+	}
+}
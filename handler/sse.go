@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// ContentTypeEventStream selects GraphQL over Server-Sent Events when accepted by the client.
+const ContentTypeEventStream = "text/event-stream"
+
+// sseKeepAlive is how often a comment is written to idle streams so proxies
+// do not time the connection out.
+const sseKeepAlive = 15 * time.Second
+
+// acceptsEventStream reports whether the client asked for a Server-Sent Events response.
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ContentTypeEventStream)
+}
+
+// serveSSE runs one operation in the "distinct connections" mode of GraphQL
+// over SSE: every result is sent as a `next` event and the stream ends with a
+// `complete` event. Subscriptions stream until they end or the client goes away.
+func (h *Handler) serveSSE(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	op, err := h.prepare(ctx, r, NewRequestOptions(r))
+	// EventSource can only GET, so subscriptions are fine there
+	if message := refuseGET(r, op, ast.OperationTypeQuery, ast.OperationTypeSubscription); message != "" {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeRequestError(w, ContentTypeJSON, http.StatusMethodNotAllowed, message)
+		return
+	}
+	if err == nil && h.limits.enabled() {
+		err = h.limits.check(op)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeEventStream+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	writeEvent := func(event string, result *graphql.Result) {
+		data := []byte{}
+		if result != nil {
+			h.formatErrors(ctx, result)
+			data, _ = json.Marshal(result)
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	switch {
+	case err != nil:
+		writeEvent("next", &graphql.Result{Errors: []gqlerrors.FormattedError{*err}})
+	case !isSubscription(op):
+		writeEvent("next", h.do(op))
+	default:
+		h.streamSubscription(ctx, w, flusher, op.params, writeEvent)
+	}
+	writeEvent("complete", nil)
+}
+
+func (h *Handler) streamSubscription(ctx context.Context, w http.ResponseWriter, flusher http.Flusher,
+	params graphql.Params, writeEvent func(string, *graphql.Result)) {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	results := graphql.Subscribe(params)
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			// keep draining after the client left so graphql-go's goroutine can exit
+			if ctx.Err() == nil {
+				writeEvent("next", result)
+			}
+		case <-ticker.C:
+			if ctx.Err() == nil {
+				fmt.Fprint(w, ":\n\n")
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"context"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveSSE(t *testing.T, h *handler.Handler, ctx context.Context, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestSSE_Subscription(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: countdownSchema(t)})
+	resp := serveSSE(t, h, context.Background(), `{"query": "subscription { countdown(from: 2) }"}`)
+
+	if contentType := resp.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+	expected := "event: next\ndata: {\"data\":{\"countdown\":2}}\n\n" +
+		"event: next\ndata: {\"data\":{\"countdown\":1}}\n\n" +
+		"event: complete\ndata: \n\n"
+	if body := resp.Body.String(); body != expected {
+		t.Fatalf("unexpected stream:\n%s", body)
+	}
+}
+
+func TestSSE_QueryAndErrors(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: countdownSchema(t)})
+
+	resp := serveSSE(t, h, context.Background(), `{"query": "{ hello }"}`)
+	expected := "event: next\ndata: {\"data\":{\"hello\":\"world\"}}\n\nevent: complete\ndata: \n\n"
+	if body := resp.Body.String(); body != expected {
+		t.Fatalf("unexpected stream:\n%s", body)
+	}
+
+	resp = serveSSE(t, h, context.Background(), `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "unknown"}}}`)
+	if body := resp.Body.String(); !strings.Contains(body, "PERSISTED_QUERY_NOT_FOUND") || !strings.HasSuffix(body, "event: complete\ndata: \n\n") {
+		t.Fatalf("expected an error followed by complete:\n%s", body)
+	}
+}
+
+func TestSSE_ClientDisconnect(t *testing.T) {
+	ticks := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"ticks": &graphql.Field{
+				Type: graphql.Int,
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					ch := make(chan interface{})
+					go func() {
+						defer close(ch)
+						for i := 0; ; i++ {
+							select {
+							case ch <- i:
+							case <-p.Context.Done():
+								return
+							}
+						}
+					}()
+					return ch, nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:        countdownSchema(t).QueryType(),
+		Subscription: ticks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(&handler.Config{Schema: &schema})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		serveSSE(t, h, ctx, `{"query": "subscription { ticks }"}`)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end after the client went away")
+	}
+}
+
+func TestSSE_GET(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: counterSchema(t)})
+	for query, expectedStatusCode := range map[string]int{
+		"subscription{__typename}": http.StatusOK,
+		"mutation{increment}":      http.StatusMethodNotAllowed,
+	} {
+		req, _ := http.NewRequest("GET", "/graphql?query="+url.QueryEscape(query), nil)
+		req.Header.Set("Accept", "text/event-stream")
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		if resp.Code != expectedStatusCode {
+			t.Fatalf("%s: expected status %d, got %d", query, expectedStatusCode, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// deadlineKey carries the context ending at the deadline of an operation with
+// a timeout. It is kept out of graphql.Params.Context because graphql-go
+// discards all data once that context is done; resolvers receive it instead,
+// see timeoutExtension.
+type deadlineKey struct{}
+
+// deadlineContext keeps the values of a resolver's context, such as its
+// span, while taking cancellation from the operation's deadline.
+type deadlineContext struct {
+	context.Context
+	deadline context.Context
+}
+
+func (c deadlineContext) Deadline() (time.Time, bool) { return c.deadline.Deadline() }
+func (c deadlineContext) Done() <-chan struct{}       { return c.deadline.Done() }
+func (c deadlineContext) Err() error                  { return c.deadline.Err() }
+
+// timeoutError is reported for every field whose resolver gave up because the
+// operation timed out.
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("operation timed out after %v", e.timeout)
+}
+
+func (e timeoutError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "TIMEOUT"}
+}
+
+// doWithTimeout executes params, bounding queries and mutations by the
+// handler's timeout. Resolvers are cancelled through their context, so the
+// rest of the operation completes and partial data is returned; fields that
+// failed with context.DeadlineExceeded are then reported as timed out.
+func (h *Handler) doWithTimeout(params graphql.Params) *graphql.Result {
+	if h.timeout <= 0 {
+		return graphql.Do(params)
+	}
+	ctx, cancel := context.WithTimeout(params.Context, h.timeout)
+	defer cancel()
+	params.Context = context.WithValue(params.Context, deadlineKey{}, ctx)
+	result := graphql.Do(params)
+
+	if ctx.Err() == nil {
+		return result
+	}
+	for i, err := range result.Errors {
+		if len(err.Path) > 0 && errors.Is(resolverError(err), context.DeadlineExceeded) {
+			result.Errors[i] = gqlerrors.FormatError(&gqlerrors.Error{
+				Message:       timeoutError{h.timeout}.Error(),
+				Locations:     err.Locations,
+				Path:          err.Path,
+				OriginalError: timeoutError{h.timeout},
+			})
+		}
+	}
+	return result
+}
+
+// resolverError returns the error a resolver failed with, which graphql-go
+// wraps to locate it.
+func resolverError(err gqlerrors.FormattedError) error {
+	if located, ok := err.OriginalError().(*gqlerrors.Error); ok {
+		return located.OriginalError
+	}
+	return err.OriginalError()
+}
+
+// timeoutExtension gives the resolvers of operations with a timeout a context
+// ending at its deadline. Resolvers that ignore their context run to
+// completion and keep their result. It must precede the extensions deriving
+// resolver contexts, such as tracingExtension.
+type timeoutExtension struct{}
+
+var _ graphql.Extension = timeoutExtension{}
+
+func (timeoutExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (timeoutExtension) Name() string {
+	return "timeout"
+}
+
+func (timeoutExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (timeoutExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (timeoutExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	if deadline, ok := ctx.Value(deadlineKey{}).(context.Context); ok {
+		ctx = deadlineContext{Context: ctx, deadline: deadline}
+	}
+	return ctx, func(*graphql.Result) {}
+}
+
+func (timeoutExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
+
+func (timeoutExtension) HasResult() bool {
+	return false
+}
+
+func (timeoutExtension) GetResult(context.Context) interface{} {
+	return nil
+}
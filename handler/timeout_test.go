@@ -0,0 +1,135 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowSchema has a `fast` field read from the root object, a `slow` field returning once its context is
+// done and a `stuck` field ignoring its context for 100ms.
+func slowSchema(t *testing.T) *graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"fast": &graphql.Field{
+					Type: graphql.String,
+				},
+				"slow": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						select {
+						case <-p.Context.Done():
+							return nil, p.Context.Err()
+						case <-time.After(5 * time.Second):
+							return "late", nil
+						}
+					},
+				},
+				"stuck": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						time.Sleep(100 * time.Millisecond)
+						return "late", nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestHandler_Timeout(t *testing.T) {
+	h := handler.New(&handler.Config{
+		Schema:  slowSchema(t),
+		Timeout: 50 * time.Millisecond,
+		// default resolvers are not bound by the timeout, so this resolves
+		// whether it runs before or after the slow field
+		RootObjectFn: func(ctx context.Context, r *http.Request) map[string]interface{} {
+			return map[string]interface{}{"fast": "ok"}
+		},
+	})
+
+	result := postTimeoutQuery(t, h, "{ fast slow }")
+	if result.Data["fast"] != "ok" || result.Data["slow"] != nil {
+		t.Fatalf("expected partial data, got %+v", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "TIMEOUT" || result.Errors[0].Path[0] != "slow" {
+		t.Fatalf("expected a TIMEOUT error on the field, got %+v", result)
+	}
+
+	// a resolver ignoring its context keeps its result
+	result = postTimeoutQuery(t, h, "{ fast stuck }")
+	if result.Data["stuck"] != "late" || len(result.Errors) != 0 {
+		t.Fatalf("expected the stuck field to resolve without errors, got %+v", result)
+	}
+}
+
+type timeoutResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postTimeoutQuery(t *testing.T, h *handler.Handler, query string) timeoutResult {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "`+query+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	h.ServeHTTP(rr, req)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("%s took %v despite the timeout", query, elapsed)
+	}
+
+	var result timeoutResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestHandler_SharedSchema(t *testing.T) {
+	schema := slowSchema(t)
+	var registries []*prometheus.Registry
+	var handlers []*handler.Handler
+	for i := 0; i < 2; i++ {
+		registry := prometheus.NewRegistry()
+		metrics, err := handler.NewMetrics(registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		registries = append(registries, registry)
+		handlers = append(handlers, handler.New(&handler.Config{Schema: schema, Timeout: 50 * time.Millisecond, Metrics: metrics}))
+	}
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ slow }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handlers[1].ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), "TIMEOUT") {
+		t.Fatalf("expected a TIMEOUT error, got %s", rr.Body)
+	}
+
+	labels := map[string]string{"parent_type": "Query", "field": "slow"}
+	if got := metricValue(t, registries[1], "graphql_resolver_duration_seconds", labels); got != 1 {
+		t.Fatalf("the resolver was timed %v times by the handler executing it", got)
+	}
+	if got := metricValue(t, registries[0], "graphql_resolver_duration_seconds", labels); got != 0 {
+		t.Fatalf("the resolver was timed %v times by another handler", got)
+	}
+}
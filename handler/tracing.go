@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the spans the handler creates.
+const TracerName = "github.com/chalkedgoose/act-up-api/handler"
+
+// Attribute keys of operation and resolver spans.
+const (
+	AttributeOperationName = attribute.Key("graphql.operation.name")
+	AttributeOperationType = attribute.Key("graphql.operation.type")
+	AttributeDocumentHash  = attribute.Key("graphql.document.hash")
+	AttributeErrorCount    = attribute.Key("graphql.errors.count")
+	AttributeFieldName     = attribute.Key("graphql.field.name")
+	AttributeFieldPath     = attribute.Key("graphql.field.path")
+	AttributeParentType    = attribute.Key("graphql.parent.type")
+)
+
+// extractTraceContext continues the trace of a W3C traceparent header sent with r.
+func extractTraceContext(ctx context.Context, r *http.Request) context.Context {
+	return propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+// startOperation starts the span of op as a child of ctx and returns the
+// context to execute it with, which carries the span, and a function ending
+// the span with the result.
+func (h *Handler) startOperation(ctx context.Context, op *operation) (context.Context, func(*graphql.Result)) {
+	opType, opName := op.describe()
+
+	spanName := "graphql"
+	if opType != "" {
+		spanName = opType
+	}
+	if opName != "" {
+		spanName += " " + opName
+	}
+	ctx, span := h.tracer.Start(context.WithValue(ctx, tracerKey{}, h.tracer), spanName,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			AttributeOperationName.String(opName),
+			AttributeOperationType.String(opType),
+			AttributeDocumentHash.String(documentHash(op.params.RequestString)),
+		))
+
+	return ctx, func(result *graphql.Result) {
+		if len(result.Errors) > 0 {
+			span.SetAttributes(AttributeErrorCount.Int(len(result.Errors)))
+			span.SetStatus(codes.Error, result.Errors[0].Message)
+		}
+		span.End()
+	}
+}
+
+// tracerKey carries the tracer of the handler executing an operation.
+type tracerKey struct{}
+
+// resolverSpansKey carries the resolverSpans of an operation.
+type resolverSpansKey struct{}
+
+// resolverSpans tracks the spans of the resolvers of one operation by the
+// response path of their field, so nested resolvers find their parent.
+type resolverSpans struct {
+	tracer trace.Tracer
+	ctx    context.Context
+	spans  map[string]trace.Span
+}
+
+// parent returns the context to start the span of the field at path from: that
+// of the closest enclosing field with a span, or the operation's.
+func (s *resolverSpans) parent(path []interface{}) context.Context {
+	for len(path) > 0 {
+		path = path[:len(path)-1]
+		if span, ok := s.spans[fmt.Sprint(path)]; ok {
+			return trace.ContextWithSpan(s.ctx, span)
+		}
+	}
+	return s.ctx
+}
+
+// tracingExtension starts a child span of the operation for every field with
+// a resolver of its own, using the tracer of the handler executing it. Fields
+// read by the default resolver are not traced.
+type tracingExtension struct{}
+
+var _ graphql.Extension = tracingExtension{}
+
+func (e tracingExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (e tracingExtension) Name() string {
+	return "opentelemetry"
+}
+
+func (e tracingExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e tracingExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e tracingExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	tracer, ok := ctx.Value(tracerKey{}).(trace.Tracer)
+	if !ok {
+		return ctx, func(*graphql.Result) {}
+	}
+	spans := &resolverSpans{tracer: tracer, ctx: ctx, spans: map[string]trace.Span{}}
+	spans.ctx = context.WithValue(ctx, resolverSpansKey{}, spans)
+	return spans.ctx, func(*graphql.Result) {}
+}
+
+// ResolveFieldDidStart returns the context the resolver runs with. graphql-go
+// keeps it as the context of the following fields too, which is why parents
+// are looked up by path rather than taken from ctx.
+func (e tracingExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	spans, ok := ctx.Value(resolverSpansKey{}).(*resolverSpans)
+	if !ok || !hasResolver(info) {
+		return ctx, func(interface{}, error) {}
+	}
+
+	path := info.Path.AsArray()
+	ctx, span := spans.tracer.Start(spans.parent(path), info.ParentType.Name()+"."+info.FieldName,
+		trace.WithAttributes(
+			AttributeFieldName.String(info.FieldName),
+			AttributeFieldPath.String(formatPath(path)),
+			AttributeParentType.String(info.ParentType.Name()),
+		))
+	spans.spans[fmt.Sprint(path)] = span
+
+	return ctx, func(_ interface{}, err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (e tracingExtension) HasResult() bool {
+	return false
+}
+
+func (e tracingExtension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+// hasResolver reports whether the field being resolved has a resolver of its
+// own. Introspection is not traced.
+func hasResolver(info *graphql.ResolveInfo) bool {
+	object, ok := info.ParentType.(*graphql.Object)
+	if !ok || strings.HasPrefix(object.Name(), "__") || strings.HasPrefix(info.FieldName, "__") {
+		return false
+	}
+	field, ok := object.Fields()[info.FieldName]
+	return ok && field.Resolve != nil
+}
+
+// formatPath renders a response path the way clients see it, e.g. users.0.name.
+func formatPath(path []interface{}) string {
+	formatted := ""
+	for i, key := range path {
+		if i > 0 {
+			formatted += "."
+		}
+		formatted += fmt.Sprint(key)
+	}
+	return formatted
+}
@@ -0,0 +1,163 @@
+package handler_test
+
+import (
+	"errors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tracedSchema lists two authors whose `posts` resolver records the span it
+// ran in and whether its context had a deadline, with a `broken` field that
+// always fails.
+func tracedSchema(t *testing.T, resolverSpans *sync.Map) *graphql.Schema {
+	author := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Author",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+			"posts": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					_, hasDeadline := p.Context.Deadline()
+					resolverSpans.Store(trace.SpanFromContext(p.Context).SpanContext().SpanID(), hasDeadline)
+					return 3, nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"authors": &graphql.Field{
+					Type: graphql.NewList(author),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return []map[string]interface{}{{"name": "a"}, {"name": "b"}}, nil
+					},
+				},
+				"broken": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return nil, errors.New("broken")
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestHandler_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var resolverSpans sync.Map
+	h := handler.New(&handler.Config{
+		Schema:         tracedSchema(t, &resolverSpans),
+		TracerProvider: provider,
+		Timeout:        time.Second,
+	})
+
+	req, _ := http.NewRequest("POST", "/graphql",
+		strings.NewReader(`{"query": "query Authors { authors { name posts } broken }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	if len(spans["query Authors"]) != 1 || len(spans["Query.authors"]) != 1 ||
+		len(spans["Author.posts"]) != 2 || len(spans["Query.broken"]) != 1 || len(spans) != 4 {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+
+	operation := spans["query Authors"][0]
+	if got := operation.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("traceparent was not continued, trace %s", got)
+	}
+	if got := operation.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("operation span has parent %s", got)
+	}
+	if got := spanAttribute(operation, handler.AttributeOperationName); got != "Authors" {
+		t.Fatalf("operation name %q", got)
+	}
+	if got := spanAttribute(operation, handler.AttributeOperationType); got != "query" {
+		t.Fatalf("operation type %q", got)
+	}
+	if got := spanAttribute(operation, handler.AttributeDocumentHash); len(got) != 64 {
+		t.Fatalf("document hash %q", got)
+	}
+	if operation.Status().Code != codes.Error {
+		t.Fatalf("operation with errors has status %v", operation.Status())
+	}
+
+	authors := spans["Query.authors"][0]
+	if authors.Parent().SpanID() != operation.SpanContext().SpanID() {
+		t.Fatal("Query.authors is not a child of the operation")
+	}
+	for _, posts := range spans["Author.posts"] {
+		if posts.Parent().SpanID() != authors.SpanContext().SpanID() {
+			t.Fatalf("%s is not a child of Query.authors", spanAttribute(posts, handler.AttributeFieldPath))
+		}
+		hasDeadline, ok := resolverSpans.Load(posts.SpanContext().SpanID())
+		if !ok {
+			t.Fatalf("the resolver of %s did not run in its span", spanAttribute(posts, handler.AttributeFieldPath))
+		}
+		if hasDeadline != true {
+			t.Fatalf("the resolver of %s did not get the operation's deadline", spanAttribute(posts, handler.AttributeFieldPath))
+		}
+	}
+	if got := spanAttribute(spans["Author.posts"][1], handler.AttributeFieldPath); got != "authors.1.posts" {
+		t.Fatalf("field path %q", got)
+	}
+
+	broken := spans["Query.broken"][0]
+	if broken.Status().Code != codes.Error || len(broken.Events()) != 1 {
+		t.Fatalf("the error of Query.broken was not recorded: %v %v", broken.Status(), broken.Events())
+	}
+}
+
+func TestHandler_TracingDisabled(t *testing.T) {
+	var resolverSpans sync.Map
+	h := handler.New(&handler.Config{Schema: tracedSchema(t, &resolverSpans)})
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ authors { posts } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	resolverSpans.Range(func(key, value interface{}) bool {
+		if key.(trace.SpanID).IsValid() {
+			t.Fatalf("resolver ran in span %v without a TracerProvider", key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// TrustedDocuments is a manifest of approved operations keyed by document ID.
+// A Handler configured with one only executes documents from the manifest.
+type TrustedDocuments struct {
+	byID     map[string]string
+	approved map[string]bool
+}
+
+// NewTrustedDocuments builds a manifest from document ID to GraphQL document.
+func NewTrustedDocuments(documents map[string]string) *TrustedDocuments {
+	d := &TrustedDocuments{
+		byID:     make(map[string]string, len(documents)),
+		approved: make(map[string]bool, len(documents)),
+	}
+	for id, document := range documents {
+		d.byID[id] = document
+		d.approved[document] = true
+	}
+	return d
+}
+
+// LoadTrustedDocuments reads a manifest from a JSON object mapping document IDs to documents.
+func LoadTrustedDocuments(path string) (*TrustedDocuments, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var documents map[string]string
+	if err := json.Unmarshal(b, &documents); err != nil {
+		return nil, fmt.Errorf("parse trusted documents %q: %w", path, err)
+	}
+	return NewTrustedDocuments(documents), nil
+}
+
+// Len returns the number of documents in the manifest.
+func (d *TrustedDocuments) Len() int {
+	return len(d.byID)
+}
+
+// loadTrustedDocument resolves opts.DocumentID to its document and, when the
+// handler runs in trusted mode, rejects any query text outside the manifest.
+func (h *Handler) loadTrustedDocument(opts *RequestOptions) *gqlerrors.FormattedError {
+	docs := h.trustedDocuments
+	if opts.DocumentID != "" {
+		if docs == nil {
+			return persistedQueryError("persisted documents are not enabled", "PERSISTED_QUERY_NOT_SUPPORTED")
+		}
+		document, ok := docs.byID[opts.DocumentID]
+		if !ok {
+			return persistedQueryError(fmt.Sprintf("unknown document %q", opts.DocumentID), "PERSISTED_QUERY_NOT_FOUND")
+		}
+		opts.Query = document
+		return nil
+	}
+
+	if docs != nil && !docs.approved[opts.Query] {
+		return persistedQueryError("only approved persisted documents may be executed", "PERSISTED_QUERY_NOT_ALLOWED")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql/testutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTrustedHandler(t *testing.T) *handler.Handler {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := `{"hero-name": "query { hero { name } }"}`
+	if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := handler.LoadTrustedDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler.New(&handler.Config{Schema: &testutil.StarWarsSchema, TrustedDocuments: docs})
+}
+
+func TestHandler_TrustedDocuments_ByDocumentID(t *testing.T) {
+	h := newTrustedHandler(t)
+
+	for _, body := range []string{`{"documentId": "hero-name"}`, `{"id": "hero-name"}`} {
+		req, _ := http.NewRequest("POST", "/graphql", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		result, _ := executeTest(t, h, req)
+		if !reflect.DeepEqual(result, heroNameResult) {
+			t.Fatalf("%s: wrong result, graphql result diff: %v", body, testutil.Diff(heroNameResult, result))
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/graphql?documentId=hero-name", nil)
+	result, _ := executeTest(t, h, req)
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+}
+
+func TestHandler_TrustedDocuments_Rejects(t *testing.T) {
+	h := newTrustedHandler(t)
+
+	req, _ := http.NewRequest("GET", "/graphql?documentId=unknown", nil)
+	result, _ := executeTest(t, h, req)
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_FOUND")
+
+	req, _ = http.NewRequest("GET", "/graphql?query="+url.QueryEscape(`query { hero { id } }`), nil)
+	result, _ = executeTest(t, h, req)
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_ALLOWED")
+
+	// the exact text of an approved document is still accepted
+	req, _ = http.NewRequest("GET", "/graphql?query="+url.QueryEscape(heroNameQuery), nil)
+	result, _ = executeTest(t, h, req)
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+}
+
+func TestHandler_DocumentIDWithoutManifest(t *testing.T) {
+	h := handler.New(&handler.Config{Schema: &testutil.StarWarsSchema})
+
+	req, _ := http.NewRequest("GET", "/graphql?documentId=hero-name", nil)
+	result, _ := executeTest(t, h, req)
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_SUPPORTED")
+}
+
+func TestHandler_TrustedDocuments_PersistedQueries(t *testing.T) {
+	cache := handler.NewLRUPersistedQueryCache(10)
+	h := handler.New(&handler.Config{
+		Schema:              &testutil.StarWarsSchema,
+		TrustedDocuments:    handler.NewTrustedDocuments(map[string]string{"hero-name": heroNameQuery}),
+		PersistedQueryCache: cache,
+	})
+
+	query := `query { hero { id } }`
+	result, _ := executeTest(t, h, persistedQueryRequest(t, query, sha256Hex(query)))
+	expectErrorCode(t, result, "PERSISTED_QUERY_NOT_ALLOWED")
+	if _, ok := cache.Get(context.Background(), sha256Hex(query)); ok {
+		t.Fatal("a document outside the manifest was registered")
+	}
+
+	result, _ = executeTest(t, h, persistedQueryRequest(t, heroNameQuery, sha256Hex(heroNameQuery)))
+	if !reflect.DeepEqual(result, heroNameResult) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(heroNameResult, result))
+	}
+	if _, ok := cache.Get(context.Background(), sha256Hex(heroNameQuery)); !ok {
+		t.Fatal("an approved document was not registered")
+	}
+}
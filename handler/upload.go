@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentTypeMultipartFormData carries file uploads as described by the
+// GraphQL multipart request spec.
+const ContentTypeMultipartFormData = "multipart/form-data"
+
+// uploadMemory is how much of a multipart body is kept in memory before the
+// remaining files are spooled to temporary files.
+const uploadMemory = 1 << 20
+
+// newMultipartRequestOptions implements the GraphQL multipart request spec:
+// the `operations` field holds one operation or a batch of them, and the `map`
+// field names the variables each file part is placed into. Files end up in the
+// variables as *multipart.FileHeader, which is what the Upload scalar accepts.
+func newMultipartRequestOptions(r *http.Request) ([]*RequestOptions, bool, error) {
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		return nil, false, err
+	}
+
+	operations := r.MultipartForm.Value["operations"]
+	if len(operations) != 1 {
+		return nil, false, errors.New("multipart request needs exactly one `operations` field")
+	}
+	body := []byte(strings.TrimSpace(operations[0]))
+	isBatch := len(body) > 0 && body[0] == '['
+
+	var batch []*RequestOptions
+	if isBatch {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, false, fmt.Errorf("invalid `operations`: %v", err)
+		}
+		if len(raw) == 0 {
+			return nil, false, errors.New("`operations` must contain at least one operation")
+		}
+		for _, operation := range raw {
+			batch = append(batch, decodeRequestOptions(operation))
+		}
+	} else {
+		batch = []*RequestOptions{decodeRequestOptions(body)}
+	}
+
+	var fileMap map[string][]string
+	if values := r.MultipartForm.Value["map"]; len(values) == 1 {
+		if err := json.Unmarshal([]byte(values[0]), &fileMap); err != nil {
+			return nil, false, fmt.Errorf("invalid `map`: %v", err)
+		}
+	}
+	for field, paths := range fileMap {
+		files := r.MultipartForm.File[field]
+		if len(files) != 1 {
+			return nil, false, fmt.Errorf("file %q of `map` is missing", field)
+		}
+		for _, path := range paths {
+			segments := strings.Split(path, ".")
+			var opts *RequestOptions
+			if isBatch {
+				i, err := strconv.Atoi(segments[0])
+				if err != nil || i < 0 || i >= len(batch) {
+					return nil, false, fmt.Errorf("invalid `map` path %q", path)
+				}
+				opts, segments = batch[i], segments[1:]
+			} else {
+				opts = batch[0]
+			}
+			if len(segments) < 2 || segments[0] != "variables" || opts.Variables == nil {
+				return nil, false, fmt.Errorf("invalid `map` path %q", path)
+			}
+			if err := setPath(opts.Variables, segments[1:], files[0]); err != nil {
+				return nil, false, fmt.Errorf("invalid `map` path %q: %v", path, err)
+			}
+		}
+	}
+	return batch, isBatch, nil
+}
+
+// setPath replaces the value at path below container, which must already exist.
+func setPath(container interface{}, path []string, value interface{}) error {
+	last := len(path) == 1
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if _, ok := c[path[0]]; !ok {
+			return fmt.Errorf("no variable %q", path[0])
+		}
+		if last {
+			c[path[0]] = value
+			return nil
+		}
+		return setPath(c[path[0]], path[1:], value)
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(c) {
+			return fmt.Errorf("no index %q", path[0])
+		}
+		if last {
+			c[i] = value
+			return nil
+		}
+		return setPath(c[i], path[1:], value)
+	}
+	return fmt.Errorf("cannot descend into %q", path[0])
+}
@@ -0,0 +1,157 @@
+package handler_test
+
+import (
+	"bytes"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// uploadSchema has a `read(files: [Upload!]!)` mutation echoing the uploaded files.
+func uploadSchema(t *testing.T) *graphql.Schema {
+	upload := graphql.NewScalar(graphql.ScalarConfig{
+		Name:         "Upload",
+		Serialize:    func(value interface{}) interface{} { return nil },
+		ParseValue:   func(value interface{}) interface{} { return value },
+		ParseLiteral: func(valueAST ast.Value) interface{} { return nil },
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"ok": &graphql.Field{Type: graphql.Boolean}},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"read": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+					Args: graphql.FieldConfigArgument{
+						"files": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(upload)))},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						var contents []string
+						for _, file := range p.Args["files"].([]interface{}) {
+							header := file.(*multipart.FileHeader)
+							f, err := header.Open()
+							if err != nil {
+								return nil, err
+							}
+							b, _ := ioutil.ReadAll(f)
+							f.Close()
+							contents = append(contents, header.Filename+"="+string(b))
+						}
+						return contents, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+// multipartRequest builds a request of the GraphQL multipart request spec
+// with one file part per entry of files.
+func multipartRequest(t *testing.T, operations, fileMap string, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("operations", operations)
+	w.WriteField("map", fileMap)
+	for name, contents := range files {
+		part, err := w.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(contents))
+	}
+	w.Close()
+
+	req, _ := http.NewRequest("POST", "/graphql", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestHandler_Upload(t *testing.T) {
+	cases := map[string]struct {
+		operations         string
+		fileMap            string
+		files              map[string]string
+		maxUploadSize      int64
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		"single operation": {
+			operations:         `{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null, null]}}`,
+			fileMap:            `{"a": ["variables.files.0"], "b": ["variables.files.1"]}`,
+			files:              map[string]string{"a": "first", "b": "second"},
+			maxUploadSize:      1 << 20,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"data":{"read":["a.txt=first","b.txt=second"]}}`,
+		},
+		"batch": {
+			operations: `[{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null]}},` +
+				`{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null]}}]`,
+			fileMap:            `{"a": ["0.variables.files.0", "1.variables.files.0"]}`,
+			files:              map[string]string{"a": "shared"},
+			maxUploadSize:      1 << 20,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `[{"data":{"read":["a.txt=shared"]}},{"data":{"read":["a.txt=shared"]}}]`,
+		},
+		"map points at a missing variable": {
+			operations:         `{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null]}}`,
+			fileMap:            `{"a": ["variables.other"]}`,
+			files:              map[string]string{"a": "first"},
+			maxUploadSize:      1 << 20,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"errors":[{"message":"invalid multipart request: invalid ` + "`map`" + ` path \"variables.other\": no variable \"other\"","locations":[],"extensions":{"code":"BAD_REQUEST"}}]}`,
+		},
+		"empty batch": {
+			operations:         `[]`,
+			fileMap:            `{"a": ["0.variables.files.0"]}`,
+			files:              map[string]string{"a": "first"},
+			maxUploadSize:      1 << 20,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"errors":[{"message":"invalid multipart request: ` + "`operations`" + ` must contain at least one operation","locations":[],"extensions":{"code":"BAD_REQUEST"}}]}`,
+		},
+		"uploads disabled": {
+			operations:         `{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null]}}`,
+			fileMap:            `{"a": ["variables.files.0"]}`,
+			files:              map[string]string{"a": "first"},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		"too large": {
+			operations:         `{"query": "mutation ($files: [Upload!]!) { read(files: $files) }", "variables": {"files": [null]}}`,
+			fileMap:            `{"a": ["variables.files.0"]}`,
+			files:              map[string]string{"a": strings.Repeat("x", 2048)},
+			maxUploadSize:      1024,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for tcID, tc := range cases {
+		t.Run(tcID, func(t *testing.T) {
+			h := handler.New(&handler.Config{
+				Schema:        uploadSchema(t),
+				MaxBatchSize:  2,
+				MaxUploadSize: tc.maxUploadSize,
+			})
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, multipartRequest(t, tc.operations, tc.fileMap, tc.files))
+
+			if rr.Code != tc.expectedStatusCode {
+				t.Fatalf("wrong status code, expected %v, got %v: %s", tc.expectedStatusCode, rr.Code, rr.Body)
+			}
+			if tc.expectedBody != "" && rr.Body.String() != tc.expectedBody {
+				t.Fatalf("unexpected body\n got: %s\nwant: %s", rr.Body, tc.expectedBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// recordFieldUsage records the fields selected by op, an operation of r, with
+// the client named by its headers.
+func (h *Handler) recordFieldUsage(r *http.Request, op *operation, opName string) {
+	h.fieldUsage.Record(usage.Operation{
+		Name:          opName,
+		ClientName:    r.Header.Get(ClientNameHeader),
+		ClientVersion: r.Header.Get(ClientVersionHeader),
+		Fields:        selectedFields(op),
+	})
+}
+
+// selectedFields lists the fields op selects, through fragments too, whether
+// or not they are skipped at runtime. Introspection fields are left out.
+func selectedFields(op *operation) []usage.Field {
+	if op.definition == nil {
+		return nil
+	}
+	root := operationRootType(&op.params.Schema, op.definition)
+	if root == nil {
+		return nil
+	}
+
+	c := &fieldCollector{
+		schema:    &op.params.Schema,
+		fragments: fragmentDefinitions(op.doc),
+		visited:   map[string]bool{},
+	}
+	c.selectionSet(root, op.definition.SelectionSet)
+	return c.fields
+}
+
+type fieldCollector struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	// visited holds the fragments already collected. Each is collected once
+	// however often it is spread, as Recorder.Record counts a field once per
+	// operation anyway.
+	visited map[string]bool
+	fields  []usage.Field
+}
+
+func (c *fieldCollector) selectionSet(parent graphql.Type, set *ast.SelectionSet) {
+	if set == nil {
+		return
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c.field(parent, selection)
+		case *ast.InlineFragment:
+			c.selectionSet(c.fragmentType(parent, selection.TypeCondition), selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || c.visited[name] {
+				continue
+			}
+			c.visited[name] = true
+			c.selectionSet(c.fragmentType(parent, fragment.TypeCondition), fragment.SelectionSet)
+		}
+	}
+}
+
+func (c *fieldCollector) field(parent graphql.Type, field *ast.Field) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return
+	}
+
+	var fields graphql.FieldDefinitionMap
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		fields = parent.Fields()
+	case *graphql.Interface:
+		fields = parent.Fields()
+	}
+	def, ok := fields[name]
+	if !ok {
+		return
+	}
+	c.fields = append(c.fields, usage.Field{Type: parent.Name(), Name: name})
+	fieldType, _ := graphql.GetNamed(def.Type).(graphql.Type)
+	c.selectionSet(fieldType, field.SelectionSet)
+}
+
+// fragmentType is the type a fragment with condition selects on within parent.
+func (c *fieldCollector) fragmentType(parent graphql.Type, condition *ast.Named) graphql.Type {
+	if condition != nil {
+		if t := c.schema.Type(condition.Name.Value); t != nil {
+			return t
+		}
+	}
+	return parent
+}
@@ -0,0 +1,113 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/graphql-go/graphql"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileSchema has a `me` field returning a Person, which implements Named.
+func profileSchema(t *testing.T) *graphql.Schema {
+	named := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Named",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object { return nil },
+	})
+	var person *graphql.Object
+	person = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Person",
+		Interfaces: []*graphql.Interface{named},
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":    &graphql.Field{Type: graphql.String},
+				"email":   &graphql.Field{Type: graphql.String},
+				"friends": &graphql.Field{Type: graphql.NewList(person)},
+			}
+		}),
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"me": &graphql.Field{
+					Type: person,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return map[string]interface{}{"name": "Kit", "friends": []interface{}{}}, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestHandler_FieldUsage(t *testing.T) {
+	store := usage.NewMemoryStore()
+	recorder := usage.NewRecorder(store)
+	h := handler.New(&handler.Config{Schema: profileSchema(t), FieldUsage: recorder})
+
+	for _, query := range []string{
+		`query Profile { me { ...person friends { ... on Named { name } } } __typename } ` +
+			`fragment person on Person { name me: name }`,
+		// invalid operations are not recorded
+		`query Profile { me { unknown } }`,
+		fanOutQuery(),
+	} {
+		req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "`+query+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(handler.ClientNameHeader, "web")
+		req.Header.Set(handler.ClientVersionHeader, "2.0")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if err := recorder.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	usages, err := store.List(context.Background(), now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := map[usage.Field]int64{}
+	for _, u := range usages {
+		if u.OperationName != "Profile" || u.ClientName != "web" || u.ClientVersion != "2.0" {
+			t.Fatalf("unexpected usage %+v", u)
+		}
+		recorded[u.Field] += u.Count
+	}
+	expected := map[usage.Field]int64{
+		{Type: "Query", Name: "me"}:       2,
+		{Type: "Person", Name: "name"}:    1,
+		{Type: "Person", Name: "email"}:   1,
+		{Type: "Person", Name: "friends"}: 1,
+		{Type: "Named", Name: "name"}:     1,
+	}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("unexpected field usage %v", recorded)
+	}
+}
+
+// fanOutQuery spreads each of its fragments twice, so that walking every spread
+// would select `email` 2^22 times.
+func fanOutQuery() string {
+	var b strings.Builder
+	b.WriteString(`query Profile { me { ...F0 } }`)
+	for i := 0; i < 22; i++ {
+		fmt.Fprintf(&b, " fragment F%d on Person { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	b.WriteString(" fragment F22 on Person { email }")
+	return b.String()
+}
@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQLTransportWSProtocol is the WebSocket subprotocol spoken by serveWebSocket,
+// see https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const GraphQLTransportWSProtocol = "graphql-transport-ws"
+
+// DefaultWebSocketInitTimeout bounds the wait for connection_init.
+const DefaultWebSocketInitTimeout = 10 * time.Second
+
+// DefaultWebSocketReadLimit bounds the size of a single client message in bytes.
+const DefaultWebSocketReadLimit = 1 << 20
+
+// WebSocketInitFn inspects the payload of a connection_init message. The
+// returned context is used for every operation of the connection; returning an
+// error closes the connection with 4403 Forbidden.
+type WebSocketInitFn func(ctx context.Context, payload map[string]interface{}) (context.Context, error)
+
+const (
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConnection is the server side of one graphql-transport-ws connection.
+type wsConnection struct {
+	h    *Handler
+	r    *http.Request
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	ctx         context.Context
+	initialised bool
+	acked       bool
+	operations  map[string]context.CancelFunc
+}
+
+var wsUpgrader = websocket.Upgrader{
+	Subprotocols: []string{GraphQLTransportWSProtocol},
+}
+
+// serveWebSocket upgrades r and runs operations sent over it until the client
+// disconnects or ctx is done.
+func (h *Handler) serveWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(h.wsReadLimit)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c := &wsConnection{
+		h:          h,
+		r:          r,
+		conn:       conn,
+		ctx:        ctx,
+		operations: map[string]context.CancelFunc{},
+	}
+	if conn.Subprotocol() != GraphQLTransportWSProtocol {
+		c.close(4406, "Subprotocol not acceptable")
+		return
+	}
+
+	initTimer := time.AfterFunc(h.wsInitTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.acked {
+			c.close(4408, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if errors.Is(err, websocket.ErrReadLimit) {
+			c.close(websocket.CloseMessageTooBig, "Message too big")
+			return
+		}
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			c.close(4400, "Invalid message received")
+			return
+		}
+		if !c.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle processes one client message, returning false once the connection was closed.
+func (c *wsConnection) handle(msg wsMessage) bool {
+	switch msg.Type {
+	case wsConnectionInit:
+		return c.init(msg.Payload)
+
+	case wsPing:
+		c.send(wsMessage{Type: wsPong})
+		return true
+
+	case wsPong:
+		return true
+
+	case wsSubscribe:
+		if msg.ID == "" {
+			c.close(4400, "Subscribe message requires an id")
+			return false
+		}
+		c.mu.Lock()
+		acked, ctx := c.acked, c.ctx
+		if !acked {
+			c.mu.Unlock()
+			c.close(4401, "Unauthorized")
+			return false
+		}
+		if _, exists := c.operations[msg.ID]; exists {
+			c.mu.Unlock()
+			c.close(4409, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+			return false
+		}
+		opCtx, cancel := context.WithCancel(ctx)
+		c.operations[msg.ID] = cancel
+		c.mu.Unlock()
+
+		go c.run(opCtx, msg.ID, decodeRequestOptions(msg.Payload))
+		return true
+
+	case wsComplete:
+		c.mu.Lock()
+		cancel, ok := c.operations[msg.ID]
+		delete(c.operations, msg.ID)
+		c.mu.Unlock()
+		if ok {
+			cancel()
+		}
+		return true
+
+	default:
+		c.close(4400, fmt.Sprintf("Unsupported message type %q", msg.Type))
+		return false
+	}
+}
+
+func (c *wsConnection) init(payload json.RawMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.initialised {
+		c.close(4429, "Too many initialisation requests")
+		return false
+	}
+	c.initialised = true
+
+	if c.h.wsInitFn != nil {
+		var params map[string]interface{}
+		if len(payload) > 0 {
+			json.Unmarshal(payload, &params)
+		}
+		ctx, err := c.h.wsInitFn(c.ctx, params)
+		if err != nil {
+			c.close(4403, "Forbidden")
+			return false
+		}
+		c.ctx = ctx
+	}
+
+	c.acked = true
+	c.send(wsMessage{Type: wsConnectionAck})
+	return true
+}
+
+// run executes one operation, streaming every result of a subscription as a
+// `next` message. Errors raised before execution are sent as one `error` message.
+func (c *wsConnection) run(ctx context.Context, id string, opts *RequestOptions) {
+	op, err := c.h.prepare(ctx, c.r, opts)
+	if err == nil && c.h.limits.enabled() {
+		err = c.h.limits.check(op)
+	}
+	if err != nil {
+		c.finish(ctx, id, &graphql.Result{Errors: []gqlerrors.FormattedError{*err}}, true)
+		return
+	}
+
+	if !isSubscription(op) {
+		result := c.h.do(op)
+		c.finish(ctx, id, result, failedBeforeExecution(result))
+		return
+	}
+
+	first := true
+	var last *graphql.Result
+	for result := range graphql.Subscribe(op.params) {
+		// keep draining after cancellation so graphql-go's goroutine can exit
+		if ctx.Err() != nil {
+			continue
+		}
+		if first && failedBeforeExecution(result) {
+			last = result
+			break
+		}
+		first = false
+		c.h.formatErrors(ctx, result)
+		c.sendPayload(id, wsNext, result)
+	}
+	if last != nil {
+		c.finish(ctx, id, last, true)
+		return
+	}
+	c.finish(ctx, id, nil, false)
+}
+
+// finish sends the final result of an operation followed by `complete`, unless
+// the client already completed it.
+func (c *wsConnection) finish(ctx context.Context, id string, result *graphql.Result, failed bool) {
+	c.mu.Lock()
+	_, active := c.operations[id]
+	delete(c.operations, id)
+	c.mu.Unlock()
+	if !active || ctx.Err() != nil {
+		return
+	}
+
+	if result != nil {
+		c.h.formatErrors(ctx, result)
+		if failed {
+			c.sendPayload(id, wsError, result.Errors)
+			return
+		}
+		c.sendPayload(id, wsNext, result)
+	}
+	c.send(wsMessage{ID: id, Type: wsComplete})
+}
+
+// isSubscription reports whether op is a subscription. Documents that fail to
+// parse are reported by graphql.Do instead.
+func isSubscription(op *operation) bool {
+	opType, _ := op.describe()
+	return opType == ast.OperationTypeSubscription
+}
+
+func (c *wsConnection) sendPayload(id, messageType string, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	c.send(wsMessage{ID: id, Type: messageType, Payload: b})
+}
+
+func (c *wsConnection) send(msg wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteJSON(msg)
+}
+
+// close ends the connection with a protocol close code.
+func (c *wsConnection) close(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.conn.Close()
+}
@@ -0,0 +1,220 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/chalkedgoose/act-up-api/handler"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countdownSchema has a `countdown(from: Int!)` subscription emitting from..1.
+func countdownSchema(t *testing.T) *graphql.Schema {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"hello": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return "world", nil
+				},
+			},
+		},
+	})
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"countdown": &graphql.Field{
+				Type: graphql.Int,
+				Args: graphql.FieldConfigArgument{
+					"from": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					ch := make(chan interface{})
+					go func() {
+						defer close(ch)
+						for i := p.Args["from"].(int); i > 0; i-- {
+							select {
+							case ch <- i:
+							case <-p.Context.Done():
+								return
+							}
+						}
+					}()
+					return ch, nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Subscription: subscription})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+type wsTestMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func dialWebSocket(t *testing.T, config handler.Config) *websocket.Conn {
+	server := httptest.NewServer(handler.New(&config))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{handler.GraphQLTransportWSProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg string) {
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) wsTestMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg wsTestMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != code {
+		t.Fatalf("expected close code %d, got %v", code, err)
+	}
+}
+
+func TestWebSocket_Subscription(t *testing.T) {
+	conn := dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+
+	send(t, conn, `{"type": "connection_init"}`)
+	if msg := receive(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %+v", msg)
+	}
+
+	send(t, conn, `{"type": "ping"}`)
+	if msg := receive(t, conn); msg.Type != "pong" {
+		t.Fatalf("expected pong, got %+v", msg)
+	}
+
+	send(t, conn, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { countdown(from: 2) }"}}`)
+	for _, expected := range []string{`{"data":{"countdown":2}}`, `{"data":{"countdown":1}}`} {
+		msg := receive(t, conn)
+		if msg.ID != "1" || msg.Type != "next" || string(msg.Payload) != expected {
+			t.Fatalf("expected next %s, got %+v (%s)", expected, msg, msg.Payload)
+		}
+	}
+	if msg := receive(t, conn); msg.ID != "1" || msg.Type != "complete" {
+		t.Fatalf("expected complete, got %+v", msg)
+	}
+}
+
+func TestWebSocket_QueryAndErrors(t *testing.T) {
+	conn := dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+	send(t, conn, `{"type": "connection_init"}`)
+	receive(t, conn)
+
+	send(t, conn, `{"id": "q", "type": "subscribe", "payload": {"query": "{ hello }"}}`)
+	if msg := receive(t, conn); msg.Type != "next" || string(msg.Payload) != `{"data":{"hello":"world"}}` {
+		t.Fatalf("unexpected message %+v (%s)", msg, msg.Payload)
+	}
+	if msg := receive(t, conn); msg.Type != "complete" {
+		t.Fatalf("expected complete, got %+v", msg)
+	}
+
+	send(t, conn, `{"id": "bad", "type": "subscribe", "payload": {"query": "subscription { nope }"}}`)
+	msg := receive(t, conn)
+	var errs []map[string]interface{}
+	if msg.Type != "error" || json.Unmarshal(msg.Payload, &errs) != nil || len(errs) != 1 {
+		t.Fatalf("expected a single error, got %+v (%s)", msg, msg.Payload)
+	}
+}
+
+func TestWebSocket_ClientComplete(t *testing.T) {
+	conn := dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+	send(t, conn, `{"type": "connection_init"}`)
+	receive(t, conn)
+
+	send(t, conn, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { countdown(from: 1000000) }"}}`)
+	receive(t, conn)
+	send(t, conn, `{"id": "1", "type": "complete"}`)
+	send(t, conn, `{"type": "ping"}`)
+
+	// results already in flight may still arrive, but never a complete
+	for {
+		msg := receive(t, conn)
+		if msg.Type == "pong" {
+			break
+		}
+		if msg.Type != "next" {
+			t.Fatalf("unexpected message after client complete: %+v", msg)
+		}
+	}
+}
+
+func TestWebSocket_ProtocolViolations(t *testing.T) {
+	conn := dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+	send(t, conn, `{"id": "1", "type": "subscribe", "payload": {"query": "{ hello }"}}`)
+	expectClose(t, conn, 4401)
+
+	conn = dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+	send(t, conn, `{"type": "connection_init"}`)
+	receive(t, conn)
+	send(t, conn, `{"type": "connection_init"}`)
+	expectClose(t, conn, 4429)
+
+	conn = dialWebSocket(t, handler.Config{Schema: countdownSchema(t)})
+	send(t, conn, `not json`)
+	expectClose(t, conn, 4400)
+
+	conn = dialWebSocket(t, handler.Config{Schema: countdownSchema(t), WebSocketInitTimeout: 10 * time.Millisecond})
+	expectClose(t, conn, 4408)
+
+	conn = dialWebSocket(t, handler.Config{Schema: countdownSchema(t), WebSocketReadLimit: 64})
+	send(t, conn, `{"type": "connection_init", "payload": {"token": "`+strings.Repeat("x", 64)+`"}}`)
+	expectClose(t, conn, websocket.CloseMessageTooBig)
+}
+
+func TestWebSocket_InitFn(t *testing.T) {
+	config := handler.Config{
+		Schema: countdownSchema(t),
+		WebSocketInitFn: func(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+			if payload["token"] != "secret" {
+				return nil, errors.New("bad token")
+			}
+			return ctx, nil
+		},
+	}
+
+	conn := dialWebSocket(t, config)
+	send(t, conn, `{"type": "connection_init", "payload": {"token": "nope"}}`)
+	expectClose(t, conn, 4403)
+
+	conn = dialWebSocket(t, config)
+	send(t, conn, `{"type": "connection_init", "payload": {"token": "secret"}}`)
+	if msg := receive(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %+v", msg)
+	}
+}
@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer is how many undelivered events a subscriber may fall behind
+// before further events to it are dropped.
+const subscriberBuffer = 32
+
+// PubSub fans out events published on a topic to every current subscriber of
+// that topic, within a single process.
+type PubSub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan interface{}]struct{}
+}
+
+func New() *PubSub {
+	return &PubSub{subscribers: map[string]map[chan interface{}]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the payloads published on topic until
+// ctx is done, at which point the channel is closed. The channel type matches
+// what graphql-go expects from a subscription field's Subscribe function.
+func (ps *PubSub) Subscribe(ctx context.Context, topic string) chan interface{} {
+	ch := make(chan interface{}, subscriberBuffer)
+
+	ps.mu.Lock()
+	if ps.subscribers[topic] == nil {
+		ps.subscribers[topic] = map[chan interface{}]struct{}{}
+	}
+	ps.subscribers[topic][ch] = struct{}{}
+	ps.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		ps.mu.Lock()
+		delete(ps.subscribers[topic], ch)
+		if len(ps.subscribers[topic]) == 0 {
+			delete(ps.subscribers, topic)
+		}
+		close(ch)
+		ps.mu.Unlock()
+	}()
+	return ch
+}
+
+// Publish delivers payload to the subscribers of topic without blocking; a
+// subscriber whose buffer is full misses the event.
+func (ps *PubSub) Publish(topic string, payload interface{}) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	for ch := range ps.subscribers[topic] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
+
+type pubSubKey struct{}
+
+// WithPubSub returns a copy of ctx carrying ps, so resolvers can publish and subscribe.
+func WithPubSub(ctx context.Context, ps *PubSub) context.Context {
+	return context.WithValue(ctx, pubSubKey{}, ps)
+}
+
+// FromContext returns the PubSub stored by WithPubSub.
+func FromContext(ctx context.Context) (*PubSub, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	ps, ok := ctx.Value(pubSubKey{}).(*PubSub)
+	return ps, ok
+}
@@ -0,0 +1,56 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/pubsub"
+)
+
+func TestPubSub_DeliversToTopicSubscribers(t *testing.T) {
+	ps := pubsub.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := ps.Subscribe(ctx, "userCreated")
+	b := ps.Subscribe(ctx, "userCreated")
+	other := ps.Subscribe(ctx, "userUpdated")
+
+	ps.Publish("userCreated", "kit")
+
+	for _, ch := range []chan interface{}{a, b} {
+		select {
+		case got := <-ch:
+			if got != "kit" {
+				t.Fatalf("unexpected payload %v", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("payload was not delivered")
+		}
+	}
+	select {
+	case got := <-other:
+		t.Fatalf("other topic received %v", got)
+	default:
+	}
+}
+
+func TestPubSub_ClosesWhenContextDone(t *testing.T) {
+	ps := pubsub.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ps.Subscribe(ctx, "userCreated")
+
+	cancel()
+	select {
+	case _, open := <-ch:
+		if open {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed")
+	}
+
+	// publishing after unsubscribe must not panic on the closed channel
+	ps.Publish("userCreated", "kit")
+}
@@ -0,0 +1,82 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept entirely in memory, intended for tests.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	usages map[usageKey]int64
+}
+
+// usageKey identifies the count of a Usage.
+type usageKey struct {
+	hour          int64
+	field         Field
+	operationName string
+	clientName    string
+	clientVersion string
+}
+
+func keyOf(u Usage) usageKey {
+	return usageKey{
+		hour:          u.Hour.UnixNano(),
+		field:         u.Field,
+		operationName: u.OperationName,
+		clientName:    u.ClientName,
+		clientVersion: u.ClientVersion,
+	}
+}
+
+func (k usageKey) usage(count int64) Usage {
+	return Usage{
+		Hour:          time.Unix(0, k.hour).UTC(),
+		Field:         k.field,
+		OperationName: k.operationName,
+		ClientName:    k.clientName,
+		ClientVersion: k.clientVersion,
+		Count:         count,
+	}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usages: map[usageKey]int64{}}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, usages []Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range usages {
+		s.usages[keyOf(u)] += u.Count
+	}
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, since, until time.Time) ([]Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var usages []Usage
+	for key, count := range s.usages {
+		if key.hour >= since.UnixNano() && key.hour < until.UnixNano() {
+			usages = append(usages, key.usage(count))
+		}
+	}
+	return usages, nil
+}
+
+func (s *MemoryStore) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.usages {
+		if key.hour < before.UnixNano() {
+			delete(s.usages, key)
+		}
+	}
+	return nil
+}
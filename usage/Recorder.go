@@ -0,0 +1,134 @@
+package usage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxNameLength bounds the operation names, client names and client versions
+// recorded, which clients choose freely.
+const maxNameLength = 128
+
+// maxPendingKeys bounds the counts kept between two flushes. Once it is
+// reached, operations with new names or clients are counted anonymously so the
+// fields they selected still count.
+const maxPendingKeys = 10_000
+
+// Operation describes an executed operation whose fields are recorded.
+type Operation struct {
+	Name          string
+	ClientName    string
+	ClientVersion string
+	// Fields are the fields the operation selected, in any order and with repeats.
+	Fields []Field
+}
+
+// Recorder counts the fields selected by operations in memory and adds the
+// counts to a Store when flushed, keeping the store off the request path.
+type Recorder struct {
+	store Store
+
+	mu      sync.Mutex
+	pending map[usageKey]int64
+}
+
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store, pending: map[usageKey]int64{}}
+}
+
+// Record counts one use of each field selected by op in the current hour.
+func (r *Recorder) Record(op Operation) {
+	hour := time.Now().UTC().Truncate(time.Hour)
+	name, clientName, clientVersion := truncate(op.Name), truncate(op.ClientName), truncate(op.ClientVersion)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[Field]bool, len(op.Fields))
+	for _, field := range op.Fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		key := keyOf(Usage{
+			Hour:          hour,
+			Field:         field,
+			OperationName: name,
+			ClientName:    clientName,
+			ClientVersion: clientVersion,
+		})
+		if _, ok := r.pending[key]; !ok && len(r.pending) >= maxPendingKeys {
+			key = keyOf(Usage{Hour: hour, Field: field})
+		}
+		r.pending[key]++
+	}
+}
+
+// truncate shortens s to at most maxNameLength bytes without splitting a rune.
+func truncate(s string) string {
+	if len(s) <= maxNameLength {
+		return s
+	}
+	i := maxNameLength
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i]
+}
+
+// Flush adds the counts recorded since the last flush to the store. Counts
+// the store fails to add are kept for the next flush.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[usageKey]int64{}
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	usages := make([]Usage, 0, len(pending))
+	for key, count := range pending {
+		usages = append(usages, key.usage(count))
+	}
+	if err := r.store.Add(ctx, usages); err != nil {
+		r.mu.Lock()
+		for key, count := range pending {
+			r.pending[key] += count
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run flushes the recorder every interval until ctx is done, then one last time.
+// Once an hour it also prunes the usage older than RetentionDays from the store.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				log.Printf("failed to flush field usage, error: %v", err)
+			}
+			if time.Since(pruned) >= time.Hour {
+				pruned = time.Now()
+				if err := r.store.Prune(ctx, pruned.AddDate(0, 0, -RetentionDays)); err != nil {
+					log.Printf("failed to prune field usage, error: %v", err)
+				}
+			}
+		case <-ctx.Done():
+			if err := r.Flush(context.Background()); err != nil {
+				log.Printf("failed to flush field usage, error: %v", err)
+			}
+			return
+		}
+	}
+}
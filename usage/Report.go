@@ -0,0 +1,41 @@
+package usage
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Report completes counts with the fields of schema's object and interface
+// types that were not used, with a zero count, most used first. Counts of
+// fields no longer in the schema are kept. Introspection types are left out.
+func Report(schema *graphql.Schema, counts []FieldCount) []FieldCount {
+	report := append([]FieldCount(nil), counts...)
+	used := make(map[Field]bool, len(counts))
+	for _, count := range counts {
+		used[count.Field] = true
+	}
+
+	for name, t := range schema.TypeMap() {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		var fields graphql.FieldDefinitionMap
+		switch t := t.(type) {
+		case *graphql.Object:
+			fields = t.Fields()
+		case *graphql.Interface:
+			fields = t.Fields()
+		default:
+			continue
+		}
+		for fieldName := range fields {
+			field := Field{Type: name, Name: fieldName}
+			if !used[field] {
+				report = append(report, FieldCount{Field: field, Operations: []string{}, Clients: []string{}})
+			}
+		}
+	}
+	sortCounts(report)
+	return report
+}
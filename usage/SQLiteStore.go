@@ -0,0 +1,89 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createFieldUsageTable = `
+CREATE TABLE IF NOT EXISTS field_usage (
+	hour           INTEGER NOT NULL,
+	type_name      TEXT NOT NULL,
+	field_name     TEXT NOT NULL,
+	operation_name TEXT NOT NULL,
+	client_name    TEXT NOT NULL,
+	client_version TEXT NOT NULL,
+	count          INTEGER NOT NULL,
+	PRIMARY KEY (hour, type_name, field_name, operation_name, client_name, client_version)
+)`
+
+// SQLiteStore is a Store backed by an embedded SQLite database.
+// The caller owns db and is responsible for registering a driver and closing it.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the field_usage table if needed and returns a store using db.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (*SQLiteStore, error) {
+	if _, err := db.ExecContext(ctx, createFieldUsageTable); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Add(ctx context.Context, usages []Usage) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO field_usage (hour, type_name, field_name, operation_name, client_name, client_version, count)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (hour, type_name, field_name, operation_name, client_name, client_version)
+DO UPDATE SET count = count + excluded.count`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, u := range usages {
+		_, err := stmt.ExecContext(ctx, u.Hour.UnixNano(), u.Field.Type, u.Field.Name,
+			u.OperationName, u.ClientName, u.ClientVersion, u.Count)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) List(ctx context.Context, since, until time.Time) ([]Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT hour, type_name, field_name, operation_name, client_name, client_version, count
+FROM field_usage WHERE hour >= ? AND hour < ?`, since.UnixNano(), until.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []Usage
+	for rows.Next() {
+		var u Usage
+		var hour int64
+		err := rows.Scan(&hour, &u.Field.Type, &u.Field.Name,
+			&u.OperationName, &u.ClientName, &u.ClientVersion, &u.Count)
+		if err != nil {
+			return nil, err
+		}
+		u.Hour = time.Unix(0, hour).UTC()
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+func (s *SQLiteStore) Prune(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM field_usage WHERE hour < ?`, before.UnixNano())
+	return err
+}
@@ -0,0 +1,130 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Field names a field of an object or interface type of the schema.
+type Field struct {
+	Type string
+	Name string
+}
+
+// Usage counts how many operations with the same name, sent by the same
+// client, selected a field within an hour.
+type Usage struct {
+	Hour          time.Time
+	Field         Field
+	OperationName string
+	ClientName    string
+	ClientVersion string
+	Count         int64
+}
+
+// RetentionDays is how many days of usage Recorder.Run keeps in its store.
+const RetentionDays = 366
+
+// Store persists the usage of fields.
+type Store interface {
+	// Add increments the stored counts by those of usages.
+	Add(ctx context.Context, usages []Usage) error
+	// List returns the stored usage of the hours starting at or after since and
+	// before until.
+	List(ctx context.Context, since, until time.Time) ([]Usage, error)
+	// Prune deletes the stored usage of the hours starting before before.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// FieldCount sums the usage of a field over a time window.
+type FieldCount struct {
+	Field Field
+	Count int64
+	// Operations and Clients are the names of the operations and clients that
+	// selected the field, sorted. Anonymous ones are left out.
+	Operations []string
+	Clients    []string
+	// LastUsed is the start of the last hour the field was selected in, zero
+	// when it was not.
+	LastUsed time.Time
+}
+
+// Summarize sums usages by field, most used first.
+func Summarize(usages []Usage) []FieldCount {
+	type summary struct {
+		count      FieldCount
+		operations map[string]bool
+		clients    map[string]bool
+	}
+	byField := map[Field]*summary{}
+	for _, u := range usages {
+		s, ok := byField[u.Field]
+		if !ok {
+			s = &summary{
+				count:      FieldCount{Field: u.Field},
+				operations: map[string]bool{},
+				clients:    map[string]bool{},
+			}
+			byField[u.Field] = s
+		}
+		s.count.Count += u.Count
+		if u.Hour.After(s.count.LastUsed) {
+			s.count.LastUsed = u.Hour
+		}
+		if u.OperationName != "" {
+			s.operations[u.OperationName] = true
+		}
+		if u.ClientName != "" {
+			s.clients[u.ClientName] = true
+		}
+	}
+
+	counts := make([]FieldCount, 0, len(byField))
+	for _, s := range byField {
+		s.count.Operations = sortedKeys(s.operations)
+		s.count.Clients = sortedKeys(s.clients)
+		counts = append(counts, s.count)
+	}
+	sortCounts(counts)
+	return counts
+}
+
+// sortCounts orders counts by descending count, then by type and field name.
+func sortCounts(counts []FieldCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Field.Type != b.Field.Type {
+			return a.Field.Type < b.Field.Type
+		}
+		return a.Field.Name < b.Field.Name
+	})
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type storeKey struct{}
+
+// WithStore returns a copy of ctx carrying store, so resolvers can report usage.
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// FromContext returns the store stored by WithStore.
+func FromContext(ctx context.Context) (Store, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	store, ok := ctx.Value(storeKey{}).(Store)
+	return store, ok
+}
@@ -0,0 +1,241 @@
+package usage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkedgoose/act-up-api/usage"
+	"github.com/graphql-go/graphql"
+	_ "modernc.org/sqlite"
+)
+
+var (
+	hour      = time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	userName  = usage.Field{Type: "User", Name: "name"}
+	userID    = usage.Field{Type: "User", Name: "id"}
+	queryUser = usage.Field{Type: "Query", Name: "user"}
+)
+
+func newSQLiteStore(t *testing.T) usage.Store {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a fresh database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store, err := usage.NewSQLiteStore(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func forEachStore(t *testing.T, fn func(t *testing.T, store usage.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, usage.NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, newSQLiteStore(t))
+	})
+}
+
+func TestStore_AddAndList(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store usage.Store) {
+		ctx := context.Background()
+		err := store.Add(ctx, []usage.Usage{
+			{Hour: hour, Field: userName, OperationName: "Profile", ClientName: "web", ClientVersion: "1", Count: 2},
+			{Hour: hour.Add(-48 * time.Hour), Field: userName, Count: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Add(ctx, []usage.Usage{
+			{Hour: hour, Field: userName, OperationName: "Profile", ClientName: "web", ClientVersion: "1", Count: 3},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		usages, err := store.List(ctx, hour.Add(-24*time.Hour), hour.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []usage.Usage{
+			{Hour: hour, Field: userName, OperationName: "Profile", ClientName: "web", ClientVersion: "1", Count: 5},
+		}
+		if !reflect.DeepEqual(usages, expected) {
+			t.Fatalf("unexpected usages %+v", usages)
+		}
+	})
+}
+
+func TestStore_Prune(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store usage.Store) {
+		ctx := context.Background()
+		err := store.Add(ctx, []usage.Usage{
+			{Hour: hour, Field: userName, Count: 1},
+			{Hour: hour.Add(-time.Hour), Field: userName, Count: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Prune(ctx, hour); err != nil {
+			t.Fatal(err)
+		}
+
+		usages, err := store.List(ctx, hour.Add(-24*time.Hour), hour.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []usage.Usage{{Hour: hour, Field: userName, Count: 1}}
+		if !reflect.DeepEqual(usages, expected) {
+			t.Fatalf("unexpected usages %+v", usages)
+		}
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	counts := usage.Summarize([]usage.Usage{
+		{Hour: hour, Field: userName, OperationName: "Profile", ClientName: "web", Count: 2},
+		{Hour: hour.Add(time.Hour), Field: userName, OperationName: "List", ClientName: "ios", Count: 1},
+		{Hour: hour, Field: userName, Count: 1},
+		{Hour: hour, Field: userID, OperationName: "Profile", ClientName: "web", Count: 1},
+	})
+	expected := []usage.FieldCount{
+		{Field: userName, Count: 4, Operations: []string{"List", "Profile"}, Clients: []string{"ios", "web"}, LastUsed: hour.Add(time.Hour)},
+		{Field: userID, Count: 1, Operations: []string{"Profile"}, Clients: []string{"web"}, LastUsed: hour},
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("unexpected counts %+v", counts)
+	}
+}
+
+func TestReport(t *testing.T) {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.String},
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"user": &graphql.Field{Type: user}},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed := usage.Field{Type: "User", Name: "email"}
+	report := usage.Report(&schema, []usage.FieldCount{
+		{Field: queryUser, Count: 3},
+		{Field: userName, Count: 3},
+		{Field: removed, Count: 1},
+	})
+	var fields []usage.Field
+	for _, count := range report {
+		fields = append(fields, count.Field)
+	}
+	if expected := []usage.Field{queryUser, userName, removed, userID}; !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("unexpected report %+v", fields)
+	}
+	if unused := report[3]; unused.Count != 0 || !unused.LastUsed.IsZero() || unused.Operations == nil {
+		t.Fatalf("unexpected unused field %+v", unused)
+	}
+}
+
+// failingStore fails to add usage until it is told to accept it.
+type failingStore struct {
+	*usage.MemoryStore
+	fail bool
+}
+
+func (s *failingStore) Add(ctx context.Context, usages []usage.Usage) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStore.Add(ctx, usages)
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStore{MemoryStore: usage.NewMemoryStore(), fail: true}
+	recorder := usage.NewRecorder(store)
+
+	profile := usage.Operation{Name: "Profile", ClientName: "web", ClientVersion: "1",
+		Fields: []usage.Field{queryUser, userName, userName}}
+	recorder.Record(profile)
+	recorder.Record(profile)
+	if err := recorder.Flush(ctx); err == nil {
+		t.Fatal("expected the store's error")
+	}
+
+	store.fail = false
+	recorder.Record(profile)
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	usages, err := store.List(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := usage.Summarize(usages)
+	if len(counts) != 2 || counts[0].Count != 3 || counts[1].Count != 3 {
+		t.Fatalf("each field should count once per operation, kept across failed flushes: %+v", counts)
+	}
+	if lastUsed := counts[0].LastUsed; lastUsed.Minute() != 0 || lastUsed.Second() != 0 || now.Sub(lastUsed) > time.Hour {
+		t.Fatalf("usage should be recorded by hour, got %v", counts[0].LastUsed)
+	}
+}
+
+func TestRecorder_BoundsNamesAndKeys(t *testing.T) {
+	ctx := context.Background()
+	store := usage.NewMemoryStore()
+	recorder := usage.NewRecorder(store)
+
+	long := strings.Repeat("x", 127) + "é"
+	recorder.Record(usage.Operation{Name: long, ClientName: long, ClientVersion: long, Fields: []usage.Field{userName}})
+	// clients making up names are counted anonymously once too many are pending
+	for i := 0; i < 20000; i++ {
+		recorder.Record(usage.Operation{Name: fmt.Sprint("Op", i), Fields: []usage.Field{userID}})
+	}
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	usages, err := store.List(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var anonymous int64
+	for _, u := range usages {
+		if u.Field == userName && (u.OperationName != long[:127] || u.ClientName != long[:127] || u.ClientVersion != long[:127]) {
+			t.Fatalf("expected names truncated to whole runes, got %+v", u)
+		}
+		if u.Field == userID && u.OperationName == "" {
+			anonymous = u.Count
+		}
+	}
+	if len(usages) != 10001 || anonymous != 10001 {
+		t.Fatalf("expected 10000 pending counts and the rest anonymous, got %d usages and %d anonymous", len(usages), anonymous)
+	}
+	if counts := usage.Summarize(usages); counts[0].Field != userID || counts[0].Count != 20000 {
+		t.Fatalf("every use of a field should count, got %+v", counts[0])
+	}
+}